@@ -0,0 +1,84 @@
+package rex
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+var closure = regexp.MustCompile(`(\.func\d+)+$`)
+
+// Warning describes a misconfiguration of the middleware stack found by Check.
+type Warning struct {
+	Module  string
+	Message string
+}
+
+func (self Warning) String() string {
+	return fmt.Sprintf("%s: %s", self.Module, self.Message)
+}
+
+// module returns the name of the middleware module, e.g. "middleware.Compress",
+// closures returned by factories are named after their factories,
+// e.g. middleware.CompressWithOptions(...) gives "middleware.CompressWithOptions".
+func module(fn func(http.Handler) http.Handler) string {
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	name = closure.ReplaceAllString(name, "")
+	if index := strings.LastIndex(name, "/"); index >= 0 {
+		name = name[index+1:]
+	}
+	return name
+}
+
+// Check validates the middleware stack of the server & its subservers, and returns
+// the warnings of the misconfigurations which would cause subtle runtime bugs:
+// Logger not being the first module, Compress used more than once, livereload
+// used before Compress, and any other module used more than once. NOTE XSRF
+// without a secret & Recovery not being the outermost are not checked, since
+// XSRF tokens are not signed with a configured secret & there is no Recovery.
+func (self *server) Check() (warnings []Warning) {
+	var names []string
+	for _, fn := range self.middleware.stack {
+		names = append(names, module(fn))
+	}
+
+	var seen = make(map[string]bool)
+	var compressed bool
+	for index, name := range names {
+		switch {
+		case strings.HasPrefix(name, "middleware.Compress"):
+			if compressed {
+				warnings = append(warnings, Warning{name, "response is compressed more than once, use a single Compress module"})
+			}
+			compressed = true
+
+		case name == "middleware.Logger" && index > 0:
+			warnings = append(warnings, Warning{name, "should be the first module to time & log the whole chain"})
+
+		case name == "livereload.Middleware" && !compressed && contains(names[index+1:], "middleware.Compress"):
+			warnings = append(warnings, Warning{name, "should be used after Compress to inject the script into uncompressed HTML"})
+
+		case seen[name] && !strings.HasPrefix(name, "rex."):
+			warnings = append(warnings, Warning{name, "is used more than once"})
+		}
+		seen[name] = true
+	}
+
+	for _, server := range self.subservers {
+		warnings = append(warnings, server.Check()...)
+	}
+	return
+}
+
+// contains reports whether any of the names starts with the given prefix.
+func contains(names []string, prefix string) bool {
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
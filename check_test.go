@@ -0,0 +1,37 @@
+package rex
+
+import (
+	"testing"
+
+	"github.com/goanywhere/rex/livereload"
+	mw "github.com/goanywhere/rex/middleware"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestCheck(t *testing.T) {
+	Convey("rex.Check", t, func() {
+		app := New()
+		app.Use(mw.Logger, mw.Compress)
+		So(app.Check(), ShouldBeEmpty)
+
+		app = New()
+		app.Use(mw.Compress, mw.Logger, mw.CompressLevel(9))
+		warnings := app.Check()
+		So(warnings, ShouldHaveLength, 2)
+		So(warnings[0].Module, ShouldEqual, "middleware.Logger")
+		So(warnings[1].Module, ShouldEqual, "middleware.CompressWithOptions")
+
+		app = New()
+		app.Use(mw.NoCache)
+		user := app.Group("/users")
+		user.Use(livereload.Middleware, mw.Compress, mw.NoCache, mw.NoCache)
+		warnings = app.Check()
+		So(warnings, ShouldHaveLength, 2)
+		So(warnings[0].Module, ShouldEqual, "livereload.Middleware")
+		So(warnings[1].String(), ShouldEqual, "middleware.NoCache: is used more than once")
+
+		// modules added by the server itself are left alone.
+		app.build()
+		So(app.Check(), ShouldHaveLength, 2)
+	})
+}
@@ -0,0 +1,29 @@
+package rex
+
+import (
+	"context"
+	"net/http"
+)
+
+type transport struct {
+	http.RoundTripper
+	context context.Context
+}
+
+// RoundTrip attaches the inbound request's context to the outbound request,
+// unless the outbound request comes with its own context already.
+func (self *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if r.Context() == context.Background() {
+		r = r.WithContext(self.context)
+	}
+	return self.RoundTripper.RoundTrip(r)
+}
+
+// Client returns a HTTP client for downstream calls which inherit the deadline &
+// cancellation of the given inbound request, so the outbound calls are aborted
+// as soon as the client disconnects or the inbound request times out.
+func Client(r *http.Request) *http.Client {
+	return &http.Client{
+		Transport: &transport{RoundTripper: http.DefaultTransport, context: r.Context()},
+	}
+}
@@ -0,0 +1,36 @@
+package rex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestClient(t *testing.T) {
+	var done = make(chan bool)
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer downstream.Close()
+	defer close(done)
+
+	Convey("rex.Client", t, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		request, _ := http.NewRequest("GET", "/", nil)
+		request = request.WithContext(ctx)
+
+		time.AfterFunc(100*time.Millisecond, cancel)
+		start := time.Now()
+		_, err := Client(request).Get(downstream.URL)
+
+		So(err, ShouldNotBeNil)
+		So(time.Since(start), ShouldBeLessThan, time.Second)
+	})
+}
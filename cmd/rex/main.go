@@ -33,6 +33,38 @@ var commands = []cli.Command{
 				Value: 5000,
 				Usage: "port to run the application server",
 			},
+			cli.StringFlag{
+				Name:   "watch",
+				Usage:  "extra comma-separated file extensions to watch, e.g. css,tmpl",
+				EnvVar: "REX_WATCH",
+			},
+			cli.StringFlag{
+				Name:   "tags",
+				Usage:  "build tags to compile the application with",
+				EnvVar: "REX_TAGS",
+			},
+			cli.StringFlag{
+				Name:   "ldflags",
+				Usage:  "linker flags to compile the application with",
+				EnvVar: "REX_LDFLAGS",
+			},
+			cli.BoolTFlag{
+				Name:  "livereload",
+				Usage: "reload browsers after the application is rebuilt, use --livereload=false to disable",
+			},
+			cli.IntFlag{
+				Name:  "proxy",
+				Usage: "port to run the development proxy serving static assets & injecting livereload.js, e.g. 3000",
+			},
+			cli.StringFlag{
+				Name:  "static",
+				Value: "public",
+				Usage: "directory of the static assets served by the development proxy",
+			},
+			cli.StringSliceFlag{
+				Name:  "env",
+				Usage: "environment variable (KEY=VALUE) for the application, e.g. --env DEBUG=true",
+			},
 		},
 	},
 	// helper to generate a secret key.
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goanywhere/rex/livereload"
+)
+
+// uncounted drops the Content-Length of HTML responses,
+// which no longer holds once livereload.js is injected.
+type uncounted struct {
+	http.ResponseWriter
+}
+
+func (self uncounted) WriteHeader(code int) {
+	if strings.Contains(self.Header().Get("Content-Type"), "html") {
+		self.Header().Del("Content-Length")
+	}
+	self.ResponseWriter.WriteHeader(code)
+}
+
+// proxy serves the static assets under the given directory, the other requests
+// are passed to the application, with livereload.js injected into HTML responses.
+func proxy(dir string) http.Handler {
+	var target = &url.URL{Scheme: "http", Host: net.JoinHostPort("localhost", strconv.Itoa(port))}
+	var upstream = httputil.NewSingleHostReverseProxy(target)
+	var director = upstream.Director
+	upstream.Director = func(r *http.Request) {
+		director(r)
+		// plain responses to inject livereload.js into.
+		r.Header.Del("Accept-Encoding")
+	}
+	var files = http.FileServer(http.Dir(dir))
+
+	return livereload.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w = uncounted{w}
+		filename := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+		if stat, err := os.Stat(filename); err == nil && !stat.IsDir() {
+			files.ServeHTTP(w, r)
+		} else {
+			upstream.ServeHTTP(w, r)
+		}
+	}))
+}
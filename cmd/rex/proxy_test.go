@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProxy(t *testing.T) {
+	Convey("rex run --proxy", t, func() {
+		var page = "<html><head><title>rex</title></head><body>" + strings.Repeat("rex", 4096) + "</body></html>"
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			io.WriteString(w, page)
+		}))
+		defer upstream.Close()
+		defer func(origin int) { port = origin }(port)
+		port = upstream.Listener.Addr().(*net.TCPAddr).Port
+
+		tempdir, _ := ioutil.TempDir("", "rex")
+		defer os.RemoveAll(tempdir)
+		ioutil.WriteFile(filepath.Join(tempdir, "app.css"), []byte("body {}"), 0644)
+
+		server := httptest.NewServer(proxy(tempdir))
+		defer server.Close()
+
+		response, err := http.Get(server.URL + "/")
+		So(err, ShouldBeNil)
+		body, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		So(err, ShouldBeNil)
+		So(string(body), ShouldContainSubstring, `/livereload.js"></script>`)
+		So(string(body), ShouldEndWith, strings.SplitN(page, "</head>", 2)[1])
+		So(len(body), ShouldBeGreaterThan, len(page))
+
+		response, err = http.Get(server.URL + "/app.css")
+		So(err, ShouldBeNil)
+		body, _ = ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		So(string(body), ShouldEqual, "body {}")
+	})
+}
@@ -3,13 +3,19 @@ package main
 import (
 	"fmt"
 	"go/build"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
 	"os/exec"
 	"os/signal"
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	log "github.com/Sirupsen/logrus"
 	"github.com/codegangsta/cli"
@@ -23,32 +29,57 @@ import (
 )
 
 var (
-	port      int
-	watchList = regexp.MustCompile(`\.(go|html|atom|rss|xml)$`)
+	port       int
+	extensions = []string{"go", "html", "atom", "rss", "xml"}
+	watchList  = watching(extensions)
 )
 
 type app struct {
-	dir    string
-	binary string
-	args   []string
+	dir     string
+	binary  string
+	args    []string
+	tags    string
+	ldflags string
+	env     []string // extra KEY=VALUE variables for the process.
+	reload  bool     // notifies browsers via livereload after restarts.
+	proxy   int      // port of the development proxy, if any.
+	static  string   // directory of the static assets served by the proxy.
 
 	task string // script for npm.
 }
 
 // build compiles the application into rex-bin executable
 // to run & optionally compiles static assets using npm.
-func (self *app) build() {
+func (self *app) build() error {
 	var done = make(chan bool)
 	cmd.Loading(done)
+	defer func() { done <- true }()
 
 	// * try build the application into rex-bin(.exe)
-	command := exec.Command("go", "build", "-o", self.binary)
+	var args = []string{"build", "-o", self.binary}
+	if self.tags != "" {
+		args = append(args, "-tags", self.tags)
+	}
+	if self.ldflags != "" {
+		args = append(args, "-ldflags", self.ldflags)
+	}
+	command := exec.Command("go", args...)
 	command.Dir = self.dir
-	if e := command.Run(); e != nil {
-		log.Fatalf("Failed to compile the application: %v", e)
+	if output, err := command.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v\n%s", err, output)
 	}
+	return nil
+}
 
-	done <- true
+// watching compiles the pattern to match the files with any of the given extensions.
+func watching(extensions []string) *regexp.Regexp {
+	var names []string
+	for _, extension := range extensions {
+		if extension = strings.TrimPrefix(strings.TrimSpace(extension), "."); extension != "" {
+			names = append(names, regexp.QuoteMeta(extension))
+		}
+	}
+	return regexp.MustCompile(`\.(` + strings.Join(names, "|") + `)$`)
 }
 
 // run executes the runnerable executable under package binary root.
@@ -71,6 +102,7 @@ func (self *app) run() (gorun chan bool) {
 			command.Dir = self.dir
 			command.Stdout = os.Stdout
 			command.Stderr = os.Stderr
+			command.Env = append(os.Environ(), self.env...)
 			if err := command.Start(); err != nil {
 				log.Fatalf("Failed to start the process: %v\n", err)
 			}
@@ -80,10 +112,47 @@ func (self *app) run() (gorun chan bool) {
 	return
 }
 
+// rerun rebuilds & restarts the application, the running one is kept on compile
+// failures, so the runner just waits for the next change to fix them.
 func (self *app) rerun(gorun chan bool) {
-	self.build()
-	livereload.Reload()
+	if err := self.build(); err != nil {
+		log.Errorf("Failed to compile the application: %v", err)
+		return
+	}
 	gorun <- true
+	if self.reload && ready() {
+		livereload.Reload()
+	}
+}
+
+// watch coalesces the bursts of changes (e.g. saving all files at once, or
+// the changes made while rebuilding) into a single rebuild.
+func (self *app) watch(changes chan string, gorun chan bool) {
+	for filename := range changes {
+		relpath, _ := filepath.Rel(self.dir, filename)
+		log.Infof("Changes on %s detected", relpath)
+		for settled := false; !settled; {
+			select {
+			case <-changes:
+			case <-time.After(300 * time.Millisecond):
+				settled = true
+			}
+		}
+		self.rerun(gorun)
+	}
+}
+
+// ready waits (up to 5 seconds) until the application accepts connections.
+func ready() bool {
+	var address = net.JoinHostPort("localhost", strconv.Itoa(port))
+	for attempt := 0; attempt < 50; attempt++ {
+		time.Sleep(100 * time.Millisecond)
+		if conn, err := net.Dial("tcp", address); err == nil {
+			conn.Close()
+			return true
+		}
+	}
+	return false
 }
 
 // Starts activates the application server along with
@@ -94,22 +163,49 @@ func (self *app) Start() {
 	signal.Notify(channel, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-channel
-		// remove the binary package on stop.
-		os.Remove(self.binary)
+		// remove the binary package (along with its temporary directory) on stop.
+		os.RemoveAll(filepath.Dir(self.binary))
 		os.Exit(1)
 	}()
 
+	// serves livereload for browsers, the application injects livereload.js pointing to it.
+	if self.reload {
+		go func() {
+			if err := livereload.ListenAndServe(); err != nil {
+				log.Errorf("Failed to start the livereload server: %v", err)
+			}
+		}()
+	}
+
+	// serves the static assets & the application with livereload.js injected.
+	if self.proxy != 0 {
+		go func() {
+			log.Infof("Development proxy is listening at %d", self.proxy)
+			if err := http.ListenAndServe(fmt.Sprintf(":%d", self.proxy), proxy(self.static)); err != nil {
+				log.Errorf("Failed to start the development proxy: %v", err)
+			}
+		}()
+	}
+
 	// start waiting the signal to start running.
 	var gorun = self.run()
-	self.build()
-	gorun <- true
+	if err := self.build(); err == nil {
+		gorun <- true
+	} else {
+		log.Errorf("Failed to compile the application: %v", err)
+	}
+
+	var changes = make(chan string, 1)
+	go self.watch(changes, gorun)
 
 	watcher := fs.NewWatcher(self.dir)
 	log.Infof("Start watching: %s", self.dir)
 	watcher.Add(watchList, func(filename string) {
-		relpath, _ := filepath.Rel(self.dir, filename)
-		log.Infof("Changes on %s detected", relpath)
-		self.rerun(gorun)
+		// pending changes are picked up by the next rebuild.
+		select {
+		case changes <- filename:
+		default:
+		}
 	})
 	watcher.Start()
 }
@@ -133,10 +229,33 @@ func Run(ctx *cli.Context) {
 	}
 	app := new(app)
 	app.dir = cwd
-	app.binary = filepath.Join(os.TempDir(), "rex-bin")
+	// * build into its own temporary directory, so concurrent runners never collide.
+	tempdir, err := ioutil.TempDir("", "rex")
+	if err != nil {
+		log.Fatalf("Failed to create the temporary directory: %v", err)
+	}
+	app.binary = filepath.Join(tempdir, "rex-bin")
 	if runtime.GOOS == "windows" {
 		app.binary += ".exe"
 	}
 	app.task = ctx.String("task")
+	app.tags = ctx.String("tags")
+	app.reload = ctx.BoolT("livereload")
+	app.proxy = ctx.Int("proxy")
+	app.static = filepath.Join(cwd, ctx.String("static"))
+	if app.reload {
+		app.env = append(app.env, "LIVERELOAD=localhost:35729")
+	}
+	app.ldflags = ctx.String("ldflags")
+	// NOTE .env under the project is loaded by the application itself on startup.
+	for _, variable := range ctx.StringSlice("env") {
+		if !strings.Contains(variable, "=") {
+			log.Fatalf("Invalid environment variable %q, KEY=VALUE is expected", variable)
+		}
+		app.env = append(app.env, variable)
+	}
+	if watch := ctx.String("watch"); watch != "" {
+		watchList = watching(append(extensions, strings.Split(watch, ",")...))
+	}
 	app.Start()
 }
@@ -96,5 +96,5 @@ func main() {
 	api.Put("/", update)
 	api.Delete("/", remove)
 
-	app.Run()
+	app.MustRun()
 }
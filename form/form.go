@@ -1,24 +1,253 @@
 package form
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	. "github.com/gorilla/schema"
 )
 
 var schema = NewDecoder()
 
+var email = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+var booleans = map[string]bool{
+	"1": true, "t": true, "true": true, "on": true, "yes": true, "y": true,
+	"0": false, "f": false, "false": false, "off": false, "no": false, "n": false,
+}
+
 type Validator interface {
 	Validate() error
 }
 
+// BindError carries the errors of each field occurred while binding the form,
+// it can be sent as a JSON response directly, e.g. rex.Send(w, err), which
+// renders as {"error": "<summary>", "fields": {"<field>": "<message>"}}.
+type BindError struct {
+	Fields map[string]string
+}
+
+func (self *BindError) Error() string {
+	var messages []string
+	for field, message := range self.Fields {
+		messages = append(messages, field+" "+message)
+	}
+	sort.Strings(messages)
+	return strings.Join(messages, "; ")
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (self *BindError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"error":  self.Error(),
+		"fields": self.Fields,
+	})
+}
+
 // Parse parsed the raw query from the URL and updates request.Form,
 // decode the from to the given struct with Validator implemented.
-func parse(r *http.Request, form Validator) (err error) {
+// Pointer fields (e.g. *string, *int) are left nil when absent from the
+// form, which distinguishes missing fields from zero values for partial updates.
+func parse(r *http.Request, form Validator) error {
+	return BindValidate(r, form)
+}
+
+// BindValidate decodes the form of the request into the given struct, validates it
+// against the `enum` & `validate` tags, then its Validator implementation, if any.
+// Tag violations are returned as *BindError listing all fields, which can be sent
+// as 422 response, e.g. rex.AbortJSON(w, r, http.StatusUnprocessableEntity, err).
+//
+// Supported `validate` rules (comma-separated), e.g. `validate:"required,min=3,max=20"`:
+//
+//	required  the field must not be the zero value (or nil pointer)
+//	min=N     the minimum length of strings/slices, or the minimum value of numbers
+//	max=N     the maximum length of strings/slices, or the maximum value of numbers
+//	email     the non-empty string must be an email address
+func BindValidate(r *http.Request, form interface{}) (err error) {
 	if err = r.ParseForm(); err == nil {
 		if err = schema.Decode(form, r.Form); err == nil {
-			err = form.Validate()
+			var e = &BindError{Fields: make(map[string]string)}
+			enums(form, e.Fields)
+			validate(form, e.Fields)
+			if len(e.Fields) > 0 {
+				err = e
+			} else if validator, ok := form.(Validator); ok {
+				err = validator.Validate()
+			}
+		} else if errors, ok := err.(MultiError); ok {
+			var e = &BindError{Fields: make(map[string]string)}
+			for field, err := range errors {
+				e.Fields[field] = err.Error()
+			}
+			err = e
 		}
 	}
 	return
 }
+
+// Filters collects the bracketed query parameters under the given key,
+// e.g. filter[status]=active&filter[role]=admin&filter[role]=owner gives
+// map[string][]string{"status": {"active"}, "role": {"admin", "owner"}}.
+func Filters(r *http.Request, key string) map[string][]string {
+	var filters = make(map[string][]string)
+	for name, values := range r.URL.Query() {
+		if strings.HasPrefix(name, key+"[") && strings.HasSuffix(name, "]") {
+			field := name[len(key)+1 : len(name)-1]
+			if field != "" {
+				filters[field] = append(filters[field], values...)
+			}
+		}
+	}
+	return filters
+}
+
+// DecodeFilters decodes the bracketed query parameters under the given key
+// into the typed filter struct using its `schema` tags.
+func DecodeFilters(r *http.Request, key string, filter interface{}) error {
+	return schema.Decode(filter, url.Values(Filters(r, key)))
+}
+
+// boolean converts the boolean-ish strings, e.g. on/off sent by HTML checkboxes.
+func boolean(value string) reflect.Value {
+	if v, exists := booleans[strings.ToLower(strings.TrimSpace(value))]; exists {
+		return reflect.ValueOf(v)
+	}
+	return reflect.Value{}
+}
+
+// enums validates the string (or *string) fields with `enum` tag against the allowed
+// values, e.g. `enum:"active,inactive,pending"`, empty values are left to Validator.
+// Violations are collected into the given fields keyed by the field's form name.
+func enums(form interface{}, fields map[string]string) {
+	value := reflect.Indirect(reflect.ValueOf(form))
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+		tag := field.Tag.Get("enum")
+		// pointer fields are left nil when absent from the form.
+		item := reflect.Indirect(value.Field(index))
+		if tag == "" || item.Kind() != reflect.String {
+			continue
+		}
+		current := item.String()
+		if current == "" {
+			continue
+		}
+		allowed := strings.Split(tag, ",")
+		valid := false
+		for _, item := range allowed {
+			if strings.TrimSpace(item) == current {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			fields[nameOf(field)] = fmt.Sprintf("must be one of: %s", strings.Join(allowed, ", "))
+		}
+	}
+}
+
+// validate checks the fields of the struct against the rules of their `validate` tags,
+// violations are collected into the given fields unless reported by enums already.
+func validate(form interface{}, fields map[string]string) {
+	value := reflect.Indirect(reflect.ValueOf(form))
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+		tag := field.Tag.Get("validate")
+		name := nameOf(field)
+		if _, exists := fields[name]; tag == "" || exists {
+			continue
+		}
+		item := value.Field(index)
+		if item.Kind() == reflect.Ptr {
+			if item.IsNil() {
+				if strings.Contains(","+tag+",", ",required,") {
+					fields[name] = "is required"
+				}
+				continue
+			}
+			item = item.Elem()
+		}
+		if message := check(item, strings.Split(tag, ",")); message != "" {
+			fields[name] = message
+		}
+	}
+}
+
+// nameOf returns the form name of the struct field from its `schema` tag, if any.
+func nameOf(field reflect.StructField) string {
+	if name := strings.SplitN(field.Tag.Get("schema"), ",", 2)[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// check returns the message of the first rule the value violates, if any.
+func check(value reflect.Value, rules []string) string {
+	for _, rule := range rules {
+		units := strings.SplitN(strings.TrimSpace(rule), "=", 2)
+		switch units[0] {
+		case "required":
+			if empty(value) {
+				return "is required"
+			}
+		case "email":
+			if value.Kind() == reflect.String && value.String() != "" && !email.MatchString(value.String()) {
+				return "must be a valid email address"
+			}
+		case "min", "max":
+			if len(units) != 2 {
+				continue
+			}
+			limit, err := strconv.ParseFloat(units[1], 64)
+			if err != nil {
+				continue
+			}
+			var size float64
+			var subject = "must be"
+			switch value.Kind() {
+			case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+				size, subject = float64(value.Len()), "length must be"
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				size = float64(value.Int())
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				size = float64(value.Uint())
+			case reflect.Float32, reflect.Float64:
+				size = value.Float()
+			default:
+				continue
+			}
+			if units[0] == "min" && size < limit {
+				return fmt.Sprintf("%s at least %s", subject, units[1])
+			} else if units[0] == "max" && size > limit {
+				return fmt.Sprintf("%s at most %s", subject, units[1])
+			}
+		}
+	}
+	return ""
+}
+
+// empty checks if the value is the zero value of its type, or an empty slice/map.
+func empty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
+
+func init() {
+	schema.RegisterConverter(false, boolean)
+}
@@ -2,6 +2,7 @@ package form
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"io"
 	"net/http"
@@ -60,3 +61,173 @@ func TestParse(t *testing.T) {
 		So(response.Code, ShouldEqual, http.StatusBadRequest)
 	})
 }
+
+type account struct {
+	Username string `schema:"username"`
+	Status   string `schema:"status" enum:"active,inactive,pending"`
+}
+
+func (self *account) Validate() error {
+	return nil
+}
+
+func TestEnums(t *testing.T) {
+	Convey("rex.form.enums", t, func() {
+		values := url.Values{}
+		values.Set("username", "username")
+		values.Set("status", "active")
+
+		request, _ := http.NewRequest("GET", "/?"+values.Encode(), nil)
+		var form account
+		So(parse(request, &form), ShouldBeNil)
+		So(form.Status, ShouldEqual, "active")
+
+		values.Set("status", "deleted")
+		request, _ = http.NewRequest("GET", "/?"+values.Encode(), nil)
+		err := parse(request, &account{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldEqual, "status must be one of: active, inactive, pending")
+	})
+}
+
+type subscription struct {
+	Email     string `schema:"email"`
+	Subscribe bool   `schema:"subscribe"`
+}
+
+func (self *subscription) Validate() error {
+	return nil
+}
+
+func TestBoolean(t *testing.T) {
+	Convey("rex.form.boolean", t, func() {
+		for value, expected := range map[string]bool{
+			"on": true, "true": true, "1": true, "yes": true, "Y": true, "TRUE": true,
+			"off": false, "false": false, "0": false, "no": false, "N": false, "False": false,
+		} {
+			request, _ := http.NewRequest("GET", "/?subscribe="+value, nil)
+			form := subscription{Subscribe: !expected}
+			So(parse(request, &form), ShouldBeNil)
+			So(form.Subscribe, ShouldEqual, expected)
+		}
+
+		request, _ := http.NewRequest("GET", "/?subscribe=maybe", nil)
+		So(parse(request, &subscription{}), ShouldNotBeNil)
+	})
+}
+
+func TestBindError(t *testing.T) {
+	Convey("rex.form.BindError", t, func() {
+		request, _ := http.NewRequest("GET", "/?status=deleted", nil)
+		err := parse(request, &account{})
+		So(err, ShouldHaveSameTypeAs, &BindError{})
+
+		data, _ := json.Marshal(err)
+		So(string(data), ShouldEqual, `{"error":"status must be one of: active, inactive, pending","fields":{"status":"must be one of: active, inactive, pending"}}`)
+
+		request, _ = http.NewRequest("GET", "/?subscribe=maybe", nil)
+		err = parse(request, &subscription{})
+		So(err, ShouldHaveSameTypeAs, &BindError{})
+		So(err.(*BindError).Fields, ShouldContainKey, "subscribe")
+	})
+}
+
+type profile struct {
+	Nickname *string `schema:"nickname"`
+	Age      *int    `schema:"age"`
+	Public   *bool   `schema:"public"`
+	Status   *string `schema:"status" enum:"active,inactive"`
+}
+
+func (self *profile) Validate() error {
+	return nil
+}
+
+func TestPointers(t *testing.T) {
+	Convey("rex.form.parse (pointers)", t, func() {
+		request, _ := http.NewRequest("PATCH", "/", bytes.NewBufferString("age=0&public=on"))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		var form profile
+		So(parse(request, &form), ShouldBeNil)
+		So(form.Nickname, ShouldBeNil)
+		So(form.Status, ShouldBeNil)
+		So(form.Age, ShouldNotBeNil)
+		So(*form.Age, ShouldEqual, 0)
+		So(form.Public, ShouldNotBeNil)
+		So(*form.Public, ShouldBeTrue)
+
+		request, _ = http.NewRequest("PATCH", "/", bytes.NewBufferString("status=deleted"))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		So(parse(request, &profile{}), ShouldHaveSameTypeAs, &BindError{})
+	})
+}
+
+type filter struct {
+	Status string   `schema:"status"`
+	Roles  []string `schema:"role"`
+	Active *bool    `schema:"active"`
+}
+
+func TestFilters(t *testing.T) {
+	Convey("rex.form.Filters", t, func() {
+		request, _ := http.NewRequest("GET", "/users?filter[status]=active&filter[role]=admin&filter[role]=owner&filter[]=x&page=2", nil)
+
+		filters := Filters(request, "filter")
+		So(filters, ShouldHaveLength, 2)
+		So(filters["status"], ShouldResemble, []string{"active"})
+		So(filters["role"], ShouldResemble, []string{"admin", "owner"})
+		So(Filters(request, "sort"), ShouldBeEmpty)
+
+		var form filter
+		So(DecodeFilters(request, "filter", &form), ShouldBeNil)
+		So(form.Status, ShouldEqual, "active")
+		So(form.Roles, ShouldResemble, []string{"admin", "owner"})
+		So(form.Active, ShouldBeNil)
+	})
+}
+
+type signup struct {
+	Username string   `schema:"username" validate:"required,min=3,max=12"`
+	Email    string   `schema:"email" validate:"required,email"`
+	Age      *int     `schema:"age" validate:"min=18"`
+	Tags     []string `schema:"tag" validate:"required,max=2"`
+	Referrer *string  `schema:"referrer" validate:"required"`
+	Plan     string   `schema:"plan" enum:"free,pro"`
+}
+
+func TestBindValidate(t *testing.T) {
+	Convey("rex.form.BindValidate", t, func() {
+		values := url.Values{
+			"username": {"rex"}, "email": {"rex@example.com"}, "age": {"20"},
+			"tag": {"go"}, "referrer": {"mail"},
+		}
+		request, _ := http.NewRequest("POST", "/", bytes.NewBufferString(values.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		var form signup
+		So(BindValidate(request, &form), ShouldBeNil)
+		So(*form.Age, ShouldEqual, 20)
+
+		values = url.Values{
+			"username": {"re"}, "email": {"rex"}, "age": {"16"}, "tag": {"a", "b", "c"},
+			"plan": {"gold"},
+		}
+		request, _ = http.NewRequest("POST", "/", bytes.NewBufferString(values.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		err := BindValidate(request, new(signup))
+		So(err, ShouldHaveSameTypeAs, new(BindError))
+		So(err.(*BindError).Fields, ShouldResemble, map[string]string{
+			"username": "length must be at least 3",
+			"email":    "must be a valid email address",
+			"age":      "must be at least 18",
+			"tag":      "length must be at most 2",
+			"referrer": "is required",
+			"plan":     "must be one of: free, pro",
+		})
+
+		// Validator is still called after the tags.
+		values = url.Values{"username": {"username"}, "password": {"7"}}
+		request, _ = http.NewRequest("POST", "/", bytes.NewBufferString(values.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		So(BindValidate(request, new(user)), ShouldNotBeNil)
+	})
+}
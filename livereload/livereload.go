@@ -1,8 +1,29 @@
+// Package livereload implements the LiveReload protocol for browser's livereload.js.
+//
+// By default, Middleware serves both the WebSocket & JavaScript endpoints under
+// the application's own address. To serve them on custom paths, change URL and
+// register the handlers yourself, e.g.
+//
+//	livereload.URL.WebSocket = "/_dev/livereload"
+//	livereload.URL.JavaScript = "/_dev/livereload.js"
+//	app.Get(livereload.URL.WebSocket, livereload.ServeWebSocket)
+//	app.Get(livereload.URL.JavaScript, livereload.ServeJavaScript)
+//
+// To run it on a separate address instead (e.g. the standard port 35729),
+// call Address before ListenAndServe; the host & port are templated into
+// the served livereload.js so browsers connect to the right place. The address
+// can also be given via the LIVERELOAD variable (e.g. "localhost:35729"), which
+// is how `rex run` points the application to its own livereload server.
 package livereload
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 	"sync"
 
 	"github.com/gorilla/websocket"
@@ -27,6 +48,14 @@ var (
 		WriteBufferSize: 1024,
 	}
 
+	// host & port of the separate livereload server, if any.
+	host string
+	port int
+
+	// literals of livereload.js templated with the configured path & address.
+	jsPath    = []byte(`+"/livereload"`)
+	jsAddress = []byte(`this.host=null,this.port=35729`)
+
 	URL = struct {
 		WebSocket  string
 		JavaScript string
@@ -49,10 +78,15 @@ func Alert(message string) {
 
 // Reload sends a reload message to browser's livereload.js.
 func Reload() {
+	reloadPath(URL.WebSocket)
+}
+
+// reloadPath sends a reload message for the given changed path to browser's livereload.js.
+func reloadPath(path string) {
 	go func() {
 		var bytes, _ = json.Marshal(&reload{
 			Command: "reload",
-			Path:    URL.WebSocket,
+			Path:    path,
 			LiveCSS: true,
 		})
 		broadcast <- bytes
@@ -65,30 +99,38 @@ func run() {
 		select {
 		case tunnel := <-in:
 			mutex.Lock()
-			defer mutex.Unlock()
 			tunnels[tunnel] = true
+			mutex.Unlock()
 
 		case tunnel := <-out:
 			mutex.Lock()
-			defer mutex.Unlock()
-			delete(tunnels, tunnel)
-			close(tunnel.message)
+			remove(tunnel)
+			mutex.Unlock()
 
 		case m := <-broadcast:
+			mutex.Lock()
 			for tunnel := range tunnels {
 				select {
 				case tunnel.message <- m:
 				default:
-					mutex.Lock()
-					defer mutex.Unlock()
-					delete(tunnels, tunnel)
-					close(tunnel.message)
+					// deleting entries during range is safe for Go maps.
+					remove(tunnel)
 				}
 			}
+			mutex.Unlock()
 		}
 	}
 }
 
+// remove unregisters the tunnel & closes its message channel only once,
+// since a tunnel dropped by broadcast will still be sent to out on leaving.
+func remove(tunnel *tunnel) {
+	if _, exists := tunnels[tunnel]; exists {
+		delete(tunnels, tunnel)
+		close(tunnel.message)
+	}
+}
+
 // Serve serves as a livereload server for accepting I/O tunnel messages.
 func ServeWebSocket(w http.ResponseWriter, r *http.Request) {
 	var socket, err = upgrader.Upgrade(w, r, nil)
@@ -107,8 +149,31 @@ func ServeWebSocket(w http.ResponseWriter, r *http.Request) {
 
 // ServeJavaScript serves livereload.js for browser.
 func ServeJavaScript(w http.ResponseWriter, r *http.Request) {
+	var script = bytes.Replace(javascript, jsPath, []byte(`+`+strconv.Quote(URL.WebSocket)), 1)
+	if port != 0 {
+		script = bytes.Replace(script, jsAddress,
+			[]byte(fmt.Sprintf(`this.host=%s,this.port=%d`, strconv.Quote(host), port)), 1)
+	}
 	w.Header().Set("Content-Type", "application/javascript")
-	w.Write(javascript)
+	w.Write(script)
+}
+
+// Address configures the host & port of a separate livereload server.
+func Address(hostname string, portnum int) {
+	host, port = hostname, portnum
+}
+
+// ListenAndServe starts a separate livereload server at the configured Address,
+// livereload.js's default port 35729 will be used if no address is configured.
+func ListenAndServe() error {
+	if port == 0 {
+		Address("localhost", 35729)
+	}
+	Start()
+	mux := http.NewServeMux()
+	mux.HandleFunc(URL.WebSocket, ServeWebSocket)
+	mux.HandleFunc(URL.JavaScript, ServeJavaScript)
+	return http.ListenAndServe(net.JoinHostPort(host, strconv.Itoa(port)), mux)
 }
 
 // Start activates livereload server for accepting tunnel messages.
@@ -123,3 +188,19 @@ func Start() {
 		go run()
 	})
 }
+
+func init() {
+	// livereload.js upgraded without the literals would silently ignore the configuration.
+	for _, literal := range [][]byte{jsPath, jsAddress} {
+		if !bytes.Contains(javascript, literal) {
+			panic(fmt.Sprintf("livereload.js is missing the templated literal: %s", literal))
+		}
+	}
+	if address := os.Getenv("LIVERELOAD"); address != "" {
+		if hostname, portnum, err := net.SplitHostPort(address); err == nil {
+			if number, err := strconv.Atoi(portnum); err == nil {
+				Address(hostname, number)
+			}
+		}
+	}
+}
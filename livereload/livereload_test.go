@@ -0,0 +1,111 @@
+package livereload
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRun(t *testing.T) {
+	Start()
+
+	Convey("rex.livereload.run", t, func() {
+		var group sync.WaitGroup
+		for index := 0; index < 100; index++ {
+			group.Add(1)
+			go func() {
+				defer group.Done()
+				tunnel := &tunnel{message: make(chan []byte, 1)}
+				in <- tunnel
+				broadcast <- []byte("reload")
+				broadcast <- []byte("reload")
+				out <- tunnel
+			}()
+		}
+		group.Wait()
+
+		mutex.RLock()
+		defer mutex.RUnlock()
+		So(tunnels, ShouldBeEmpty)
+	})
+}
+
+func TestIgnore(t *testing.T) {
+	Ignore("*.map", "node_modules/*")
+
+	Convey("rex.livereload.Ignore", t, func() {
+		So(ignored("/app", "/app/assets/app.js.map"), ShouldBeTrue)
+		So(ignored("/app", "/app/node_modules/react/index.js"), ShouldBeTrue)
+		So(ignored("/app", "/app/assets/app.js"), ShouldBeFalse)
+		So(ignored("/app", "/app/views/node_modules.html"), ShouldBeFalse)
+	})
+}
+
+func TestServeJavaScript(t *testing.T) {
+	defer func(path string, hostname string, portnum int) {
+		URL.WebSocket = path
+		Address(hostname, portnum)
+	}(URL.WebSocket, host, port)
+
+	Convey("rex.livereload.ServeJavaScript", t, func() {
+		URL.WebSocket = "/_dev/livereload"
+		Address("example.com", 8765)
+
+		request, _ := http.NewRequest("GET", URL.JavaScript, nil)
+		response := httptest.NewRecorder()
+		ServeJavaScript(response, request)
+		So(response.Header().Get("Content-Type"), ShouldEqual, "application/javascript")
+
+		script := response.Body.String()
+		So(script, ShouldContainSubstring, `+"/_dev/livereload"`)
+		So(script, ShouldContainSubstring, `this.host="example.com",this.port=8765`)
+		So(script, ShouldNotContainSubstring, `+"/livereload"`)
+		So(script, ShouldNotContainSubstring, `this.port=35729`)
+	})
+}
+
+func TestWatch(t *testing.T) {
+	Start()
+	defer func(duration time.Duration) { delay = duration }(delay)
+	delay = 100 * time.Millisecond
+
+	Convey("rex.livereload.Watch", t, func() {
+		tempdir, _ := ioutil.TempDir("", "livereload")
+		defer os.RemoveAll(tempdir)
+		So(Watch(tempdir), ShouldBeNil)
+
+		tunnel := &tunnel{message: make(chan []byte, 10)}
+		in <- tunnel
+		defer func() { out <- tunnel }()
+
+		var filename string
+		for index := 0; index < 5; index++ {
+			filename = filepath.Join(tempdir, fmt.Sprintf("app%d.css", index))
+			ioutil.WriteFile(filename, []byte("body {}"), 0644)
+		}
+		time.Sleep(5 * delay)
+
+		// watchers of the previous runs may report their removed directories as well.
+		var reloads []reload
+		for len(tunnel.message) > 0 {
+			var message reload
+			json.Unmarshal(<-tunnel.message, &message)
+			if strings.HasPrefix(message.Path, tempdir) {
+				reloads = append(reloads, message)
+			}
+		}
+		So(reloads, ShouldHaveLength, 1)
+		So(reloads[0].Command, ShouldEqual, "reload")
+		So(reloads[0].Path, ShouldEqual, filename)
+	})
+}
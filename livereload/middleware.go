@@ -6,8 +6,10 @@ import (
 	"compress/zlib"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -17,12 +19,19 @@ type writer struct {
 }
 
 func (self *writer) addJavaScript(data []byte) []byte {
+	var address = self.host
+	if port != 0 {
+		address = net.JoinHostPort(host, strconv.Itoa(port))
+	}
 	javascript := fmt.Sprintf(`<script defer src="//%s%s"></script>
-</head>`, self.host, URL.JavaScript)
+</head>`, address, URL.JavaScript)
 	return regexp.MustCompile(`</head>`).ReplaceAll(data, []byte(javascript))
 }
 
+// Write reports the length of the given data on success, rather than of the injected one,
+// since io.Copy (e.g. in httputil.ReverseProxy) treats a different count as a short write.
 func (self *writer) Write(data []byte) (size int, e error) {
+	var length = len(data)
 	if strings.Contains(self.Header().Get("Content-Type"), "html") {
 		var encoding = self.Header().Get("Content-Encoding")
 		if encoding == "" {
@@ -33,33 +42,42 @@ func (self *writer) Write(data []byte) (size int, e error) {
 
 			if encoding == "gzip" {
 				// decode to add javascript reference.
-				reader, _ = gzip.NewReader(bytes.NewReader(data))
-				io.Copy(buffer, reader)
-				output := self.addJavaScript(buffer.Bytes())
-				reader.Close()
-				buffer.Reset()
-				// encode back to HTML with added javascript reference.
-				writer := gzip.NewWriter(buffer)
-				writer.Write(output)
-				writer.Close()
-				data = buffer.Bytes()
+				// partial compressed streams are passed through as they are.
+				if reader, e = gzip.NewReader(bytes.NewReader(data)); e == nil {
+					if _, e = io.Copy(buffer, reader); e == nil {
+						output := self.addJavaScript(buffer.Bytes())
+						buffer.Reset()
+						// encode back to HTML with added javascript reference.
+						writer := gzip.NewWriter(buffer)
+						writer.Write(output)
+						writer.Close()
+						data = buffer.Bytes()
+					}
+					reader.Close()
+				}
 
 			} else if encoding == "deflate" {
 				// decode to add javascript reference.
-				reader, _ = zlib.NewReader(bytes.NewReader(data))
-				io.Copy(buffer, reader)
-				output := self.addJavaScript(buffer.Bytes())
-				reader.Close()
-				buffer.Reset()
-				// encode back to HTML with added javascript reference.
-				writer := zlib.NewWriter(buffer)
-				writer.Write(output)
-				writer.Close()
-				data = buffer.Bytes()
+				// partial compressed streams are passed through as they are.
+				if reader, e = zlib.NewReader(bytes.NewReader(data)); e == nil {
+					if _, e = io.Copy(buffer, reader); e == nil {
+						output := self.addJavaScript(buffer.Bytes())
+						buffer.Reset()
+						// encode back to HTML with added javascript reference.
+						writer := zlib.NewWriter(buffer)
+						writer.Write(output)
+						writer.Close()
+						data = buffer.Bytes()
+					}
+					reader.Close()
+				}
 			}
 		}
 	}
-	return self.ResponseWriter.Write(data)
+	if _, e = self.ResponseWriter.Write(data); e != nil {
+		return 0, e
+	}
+	return length, nil
 }
 
 func Middleware(next http.Handler) http.Handler {
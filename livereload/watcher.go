@@ -0,0 +1,141 @@
+package livereload
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var (
+	// delay to wait for the rapid successive file events before reloading.
+	delay = 500 * time.Millisecond
+
+	ignores     []string
+	ignoresLock sync.RWMutex
+)
+
+// Ignore skips the file events matching any of the given glob patterns, e.g. `*.map`
+// or `node_modules/*`. Patterns are matched against the file's base name, along
+// with its path (and each of its parent directories) relative to the watched root.
+func Ignore(patterns ...string) {
+	ignoresLock.Lock()
+	defer ignoresLock.Unlock()
+	ignores = append(ignores, patterns...)
+}
+
+// ignored checks if the given file under root matches any of the ignore patterns.
+func ignored(root, filename string) bool {
+	ignoresLock.RLock()
+	defer ignoresLock.RUnlock()
+
+	relpath, err := filepath.Rel(root, filename)
+	if err != nil || strings.HasPrefix(relpath, "..") {
+		relpath = filename
+	}
+	relpath = filepath.ToSlash(relpath)
+	for _, pattern := range ignores {
+		if matched, _ := filepath.Match(pattern, filepath.Base(filename)); matched {
+			return true
+		}
+		// node_modules/* should match node_modules/a/b.js as well.
+		for index := 0; index <= len(relpath); index++ {
+			if index == len(relpath) || relpath[index] == '/' {
+				if matched, _ := path.Match(pattern, relpath[:index]); matched {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Watch monitors the given files/directories (recursively) and sends the reload
+// message with the changed file's path to browser's livereload.js automatically.
+func Watch(paths ...string) error {
+	Start()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if err = watch(watcher, path, path); err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	var wait = delay
+	go func() {
+		defer watcher.Close()
+
+		var changed string
+		var timer <-chan time.Time
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				var root = rootOf(paths, event.Name)
+				if ignored(root, event.Name) {
+					continue
+				}
+				// newly created directories need to be watched as well.
+				if event.Op&fsnotify.Create == fsnotify.Create {
+					if stat, err := os.Stat(event.Name); err == nil && stat.IsDir() {
+						watch(watcher, root, event.Name)
+					}
+				}
+				// debounce: only the last change within the delay will be reloaded.
+				changed = event.Name
+				timer = time.After(wait)
+
+			case <-timer:
+				timer = nil
+				reloadPath(changed)
+
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// rootOf finds the watched root of the given file.
+func rootOf(roots []string, filename string) string {
+	for _, root := range roots {
+		if relpath, err := filepath.Rel(root, filename); err == nil && !strings.HasPrefix(relpath, "..") {
+			return root
+		}
+	}
+	return filepath.Dir(filename)
+}
+
+// watch adds the given path along with all its sub-directories
+// (hidden & ignored ones excluded) under the watched root into watcher.
+func watch(watcher *fsnotify.Watcher, root, start string) error {
+	return filepath.Walk(start, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != start && (strings.HasPrefix(info.Name(), ".") || ignored(root, path)) {
+				return filepath.SkipDir
+			}
+			return watcher.Add(path)
+		}
+		if path == start {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,22 @@
+package rex
+
+import log "github.com/Sirupsen/logrus"
+
+// Logger is the logging interface used by the server, which is satisfied by
+// *logrus.Logger and most of the leveled loggers, e.g. zap.SugaredLogger.
+type Logger interface {
+	Debugf(format string, v ...interface{})
+	Infof(format string, v ...interface{})
+	Warnf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+	Fatalf(format string, v ...interface{})
+}
+
+// logger defaults to the standard logrus logger configured via LOG_LEVEL & LOG_FORMAT.
+var logger Logger = log.StandardLogger()
+
+// SetLogger routes the logs of the server to the given logger, e.g. to
+// use the application's own logging stack or to capture them in tests.
+func SetLogger(l Logger) {
+	logger = l
+}
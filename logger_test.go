@@ -0,0 +1,36 @@
+package rex
+
+import (
+	"fmt"
+	"testing"
+
+	mw "github.com/goanywhere/rex/middleware"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recorder struct {
+	logs []string
+}
+
+func (self *recorder) log(level, format string, v ...interface{}) {
+	self.logs = append(self.logs, level+": "+fmt.Sprintf(format, v...))
+}
+
+func (self *recorder) Debugf(format string, v ...interface{}) { self.log("debug", format, v...) }
+func (self *recorder) Infof(format string, v ...interface{})  { self.log("info", format, v...) }
+func (self *recorder) Warnf(format string, v ...interface{})  { self.log("warn", format, v...) }
+func (self *recorder) Errorf(format string, v ...interface{}) { self.log("error", format, v...) }
+func (self *recorder) Fatalf(format string, v ...interface{}) { self.log("fatal", format, v...) }
+
+func TestSetLogger(t *testing.T) {
+	Convey("rex.SetLogger", t, func() {
+		defer SetLogger(logger)
+		var recorder = new(recorder)
+		SetLogger(recorder)
+
+		app := New()
+		app.Use(mw.NoCache, mw.NoCache)
+		app.build()
+		So(recorder.logs, ShouldResemble, []string{"warn: Middleware middleware.NoCache: is used more than once"})
+	})
+}
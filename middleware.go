@@ -1,21 +1,27 @@
 package rex
 
-import "net/http"
+import (
+	"net/http"
+	"sync"
+)
 
 type middleware struct {
+	once  sync.Once
 	cache http.Handler
 	stack []func(http.Handler) http.Handler
 }
 
-// Implements the net/http Handler interface and calls the middleware stack.
+// Implements the net/http Handler interface and calls the middleware stack,
+// which is chained only once on the first request (safe for concurrent ones).
 func (self *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if self.cache == nil {
-		// setup the whole middleware modules in a FIFO chain.
+	self.once.Do(func() {
+		// setup the whole middleware modules in a FIFO chain: wrapping from the
+		// last one makes the first module the outermost, i.e. runs first.
 		var next http.Handler = http.DefaultServeMux
 		for index := len(self.stack) - 1; index >= 0; index-- {
 			next = self.stack[index](next)
 		}
 		self.cache = next
-	}
+	})
 	self.cache.ServeHTTP(w, r)
 }
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+)
+
+type body struct {
+	io.Reader
+	io.Closer
+}
+
+// hasBody checks if the request comes with a non-empty body, requests with
+// unknown length (e.g. chunked) are peeked without consuming the body.
+func hasBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	if r.ContentLength > 0 {
+		return true
+	}
+	reader := bufio.NewReader(r.Body)
+	_, err := reader.Peek(1)
+	r.Body = &body{reader, r.Body}
+	return err == nil
+}
+
+// BodyRequired rejects the requests without a body with 400,
+// e.g. app.Post("/users", middleware.BodyRequired(handler)).
+func BodyRequired(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hasBody(r) {
+			http.Error(w, "Request body is required", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BodyForbidden rejects the requests with a body with 400,
+// e.g. app.Get("/users", middleware.BodyForbidden(handler)).
+func BodyForbidden(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hasBody(r) {
+			http.Error(w, "Request body is not allowed", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goanywhere/rex"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBodyRequired(t *testing.T) {
+	app := rex.New()
+	app.Post("/", BodyRequired(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})))
+
+	Convey("rex.middleware.BodyRequired", t, func() {
+		request, _ := http.NewRequest("POST", "/", nil)
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusBadRequest)
+
+		request, _ = http.NewRequest("POST", "/", ioutil.NopCloser(strings.NewReader("rex")))
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Body.String(), ShouldEqual, "rex")
+	})
+}
+
+func TestBodyForbidden(t *testing.T) {
+	app := rex.New()
+	app.Get("/", BodyForbidden(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "app")
+	})))
+
+	Convey("rex.middleware.BodyForbidden", t, func() {
+		request, _ := http.NewRequest("GET", "/", strings.NewReader("rex"))
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusBadRequest)
+
+		request, _ = http.NewRequest("GET", "/", nil)
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+	})
+}
@@ -7,113 +7,288 @@ import (
 	"io"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/andybalholm/brotli"
 )
 
 var (
-	regexAcceptEncoding = regexp.MustCompile(`(gzip|deflate|\*)(;q=(1(\.0)?|0(\.[0-9])?))?`)
+	regexAcceptEncoding = regexp.MustCompile(`(br|gzip|deflate|\*)(;q=(1(\.0)?|0(\.[0-9])?))?`)
 	regexContentType    = regexp.MustCompile(`((message|text)\/.+)|((application\/).*(javascript|json|xml))`)
 )
 
+// CompressOptions configures the Compress middleware.
+type CompressOptions struct {
+	// MinSize is the minimum number of bytes a response body must reach
+	// before it gets compressed, smaller bodies are sent uncompressed.
+	MinSize int
+	// Level is the compression level using the compress/flate constants,
+	// invalid levels fall back to flate.DefaultCompression. Since there
+	// is no point to use flate.NoCompression here, zero value is treated
+	// as flate.DefaultCompression as well.
+	Level int
+	// Types are the compressible MIME types, e.g. image/svg+xml, wildcard
+	// subtypes like text/* are supported as well. Defaults to the common
+	// text, JavaScript, JSON & XML types when no types are given.
+	Types []string
+
+	matcher *regexp.Regexp
+}
+
+// preferences ranks the supported encodings with equal q-values, lower is better.
+var preferences = map[string]int{"br": 0, "gzip": 1, "deflate": 2, "*": 3}
+
+// compressMinSize is the default threshold of the response body size before compressing.
+const compressMinSize = 1024
+
 type compression interface {
 	io.WriteCloser
+	Flush() error
 }
 
 type compressor struct {
 	http.ResponseWriter
 	encodings []string
+	options   *CompressOptions
+
+	buffer []byte
+	output bytes.Buffer
+	writer compression
+
+	status      int
+	decided     bool
+	streaming   bool
+	wroteHeader bool
 }
 
 // AcceptEncodings fetches the requested encodings from client with priority.
+// Encodings with higher q-values come first, while Brotli is preferred over
+// gzip/deflate when the q-values are equal.
 func (self *compressor) acceptEncodings(request *http.Request) (encodings []string) {
+	var weights = make(map[string]float64)
 	// find all encodings supported by backend server.
 	matches := regexAcceptEncoding.FindAllString(request.Header.Get("Accept-Encoding"), -1)
 	for _, item := range matches {
 		units := strings.SplitN(item, ";", 2)
 		// top priority with q=1|q=1.0|Not Specified.
-		if len(units) == 1 {
+		weight := 1.0
+		if len(units) == 2 {
+			weight, _ = strconv.ParseFloat(strings.TrimPrefix(units[1], "q="), 64)
+		}
+		// not acceptable at client side.
+		if weight == 0 {
+			continue
+		}
+		if _, exists := weights[units[0]]; !exists {
 			encodings = append(encodings, units[0])
+		}
+		weights[units[0]] = weight
+	}
+	sort.SliceStable(encodings, func(i, j int) bool {
+		if weights[encodings[i]] != weights[encodings[j]] {
+			return weights[encodings[i]] > weights[encodings[j]]
+		}
+		return preferences[encodings[i]] < preferences[encodings[j]]
+	})
+	return
+}
 
-		} else {
-			if strings.HasPrefix(units[1], "q=1") {
-				// insert the specified top priority to the first.
-				encodings = append([]string{units[0]}, encodings...)
+// decide determines whether the response should be compressed using the buffered body.
+// Compressed output is kept in memory until the next Write call, so that the accurate
+// Content-Length can be set for bodies written in one go.
+func (self *compressor) decide() (err error) {
+	self.decided = true
 
-			} else if strings.HasSuffix(units[1], "0") {
-				// not acceptable at client side.
-				continue
-			} else {
-				// lower priority encoding
-				encodings = append(encodings, units[0])
+	var mimetype = self.Header().Get("Content-Type")
+	if mimetype == "" && len(self.buffer) > 0 {
+		mimetype = http.DetectContentType(self.buffer)
+		self.Header().Set("Content-Type", mimetype)
+	}
+
+	if self.Header().Get("Content-Encoding") == "" && len(self.buffer) >= self.options.MinSize &&
+		self.options.matcher.MatchString(strings.ToLower(strings.TrimSpace(strings.SplitN(mimetype, ";", 2)[0]))) {
+		// okay to start compressing, if any error occrued, fallback to ResponseWriter.
+		var encoding string
+		var level = self.options.Level
+		switch self.encodings[0] {
+		case "br":
+			encoding = "br"
+			if level < brotli.BestSpeed {
+				level = brotli.DefaultCompression
 			}
+			self.writer = brotli.NewWriterLevel(&self.output, level)
+
+		case "deflate":
+			encoding = "deflate"
+			self.writer, err = flate.NewWriter(&self.output, level)
+
+		default:
+			encoding = "gzip"
+			self.writer, err = gzip.NewWriterLevel(&self.output, level)
+		}
+		if err == nil {
+			self.Header().Set("Content-Encoding", encoding)
+			self.Header().Add("Vary", "Accept-Encoding")
+		} else {
+			// fallback to standard http.ResponseWriter, nothing happened~ (~__~"")
+			self.writer = nil
+		}
+	}
+
+	var buffer = self.buffer
+	self.buffer = nil
+	if self.writer != nil {
+		_, err = self.writer.Write(buffer)
+	} else {
+		self.writeHeader()
+		if len(buffer) > 0 {
+			_, err = self.ResponseWriter.Write(buffer)
 		}
 	}
 	return
 }
 
-func (self *compressor) filter(src []byte) ([]byte, string) {
-	var mimetype = self.Header().Get("Content-Type")
-	if mimetype == "" {
-		mimetype = http.DetectContentType(src)
-		self.Header().Set("Content-Type", mimetype)
+// stream switches the compressed output from memory to the ResponseWriter,
+// the Content-Length is unknown at this point, hence chunked encoding is used.
+func (self *compressor) stream() (err error) {
+	self.streaming = true
+	self.Header().Del("Content-Length")
+	self.writeHeader()
+	_, err = self.output.WriteTo(self.ResponseWriter)
+	return
+}
+
+// writeHeader writes the pending status code, if any.
+func (self *compressor) writeHeader() {
+	if !self.wroteHeader {
+		self.wroteHeader = true
+		if self.status != 0 {
+			self.ResponseWriter.WriteHeader(self.status)
+		}
 	}
+}
 
-	if self.Header().Get("Content-Encoding") != "" {
-		return src, ""
+// WriteHeader defers the status code until the compression has been decided,
+// since the Content-Encoding header must be set before the header is written.
+func (self *compressor) WriteHeader(status int) {
+	if self.status == 0 {
+		self.status = status
 	}
+}
 
-	if !regexContentType.MatchString(strings.TrimSpace(strings.SplitN(mimetype, ";", 2)[0])) {
-		return src, ""
+func (self *compressor) Write(data []byte) (size int, err error) {
+	if !self.decided {
+		// buffer the body until the threshold is exceeded.
+		self.buffer = append(self.buffer, data...)
+		if len(self.buffer) >= self.options.MinSize {
+			err = self.decide()
+		}
+		return len(data), err
 	}
 
-	// okay to start compressing.
-	var e error
-	var encoding string
-	var writer compression
-	var buffer *bytes.Buffer = new(bytes.Buffer)
-	// try compress the data, if any error occrued, fallback to ResponseWriter.
-	if self.encodings[0] == "deflate" {
-		encoding = "deflate"
-		writer, e = flate.NewWriter(buffer, flate.DefaultCompression)
+	if self.writer == nil {
+		return self.ResponseWriter.Write(data)
+	}
+	if !self.streaming {
+		if err = self.stream(); err != nil {
+			return
+		}
+	}
+	size, err = self.writer.Write(data)
+	if err == nil {
+		_, err = self.output.WriteTo(self.ResponseWriter)
+	}
+	return
+}
+
+// Flush sends the buffered (compressed) data to the client immediately,
+// so that streaming responses like Server-Sent Events work under compression.
+func (self *compressor) Flush() {
+	if !self.decided {
+		self.decide()
+	}
+	if self.writer != nil {
+		if !self.streaming {
+			self.stream()
+		}
+		self.writer.Flush()
+		self.output.WriteTo(self.ResponseWriter)
 	} else {
-		encoding = "gzip"
-		writer, e = gzip.NewWriterLevel(buffer, gzip.DefaultCompression)
+		self.writeHeader()
 	}
-	_, e = writer.Write(src)
-	writer.Close()
-	if e == nil {
-		return buffer.Bytes(), encoding
+	if flusher, ok := self.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
 	}
-	// fallback to standard http.ResponseWriter, nothing happened~ (~__~"")
-	return src, ""
 }
 
-func (self *compressor) Write(data []byte) (size int, err error) {
-	if bytes, encoding := self.filter(data); encoding != "" {
-		self.Header().Set("Content-Encoding", encoding)
-		self.Header().Add("Vary", "Accept-Encoding")
-		self.Header().Del("Content-Length")
-		return self.ResponseWriter.Write(bytes)
+// close decides the compression for bodies smaller than the threshold
+// and flushes the remaining compressed data after the handler finished.
+func (self *compressor) close() {
+	if !self.decided {
+		self.decide()
+	}
+	if self.writer != nil {
+		self.writer.Close()
+		if !self.streaming {
+			self.Header().Set("Content-Length", strconv.Itoa(self.output.Len()))
+			self.writeHeader()
+		}
+		self.output.WriteTo(self.ResponseWriter)
 	}
-	return self.ResponseWriter.Write(data)
 }
 
-// GZIP/Deflate compression supports.
+// Brotli/GZIP/Deflate compression supports, bodies smaller than 1KB are sent uncompressed.
 func Compress(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("Sec-WebSocket-Key") != "" || r.Method == "HEAD" {
-			next.ServeHTTP(w, r)
-		} else {
-			compressor := new(compressor)
-			compressor.ResponseWriter = w
+	return CompressWithOptions(CompressOptions{MinSize: compressMinSize})(next)
+}
+
+// CompressLevel creates the compression middleware using the given compress/flate level,
+// e.g. flate.BestSpeed for high-throughput APIs, invalid levels fall back to the default one.
+func CompressLevel(level int) func(http.Handler) http.Handler {
+	return CompressWithOptions(CompressOptions{MinSize: compressMinSize, Level: level})
+}
+
+// CompressTypes creates the compression middleware for the given MIME types only,
+// e.g. application/vnd.api+json or image/svg+xml.
+func CompressTypes(types ...string) func(http.Handler) http.Handler {
+	return CompressWithOptions(CompressOptions{MinSize: compressMinSize, Types: types})
+}
 
-			encodings := compressor.acceptEncodings(r)
-			if len(encodings) == 0 {
+// CompressWithOptions creates the Brotli/GZIP/Deflate compression middleware using the given options.
+func CompressWithOptions(options CompressOptions) func(http.Handler) http.Handler {
+	if options.Level == flate.NoCompression || options.Level < flate.HuffmanOnly || options.Level > flate.BestCompression {
+		options.Level = flate.DefaultCompression
+	}
+	if len(options.Types) == 0 {
+		options.matcher = regexContentType
+	} else {
+		var patterns []string
+		for _, mimetype := range options.Types {
+			pattern := regexp.QuoteMeta(strings.ToLower(strings.TrimSpace(mimetype)))
+			patterns = append(patterns, strings.Replace(pattern, `\*`, `.+`, -1))
+		}
+		options.matcher = regexp.MustCompile(`^(` + strings.Join(patterns, "|") + `)$`)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Sec-WebSocket-Key") != "" || r.Method == "HEAD" {
 				next.ServeHTTP(w, r)
 			} else {
-				compressor.encodings = encodings
-				next.ServeHTTP(compressor, r)
+				compressor := new(compressor)
+				compressor.ResponseWriter = w
+				compressor.options = &options
+
+				encodings := compressor.acceptEncodings(r)
+				if len(encodings) == 0 {
+					next.ServeHTTP(w, r)
+				} else {
+					compressor.encodings = encodings
+					next.ServeHTTP(compressor, r)
+					compressor.close()
+				}
 			}
-		}
-	})
+		})
+	}
 }
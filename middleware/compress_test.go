@@ -1,11 +1,19 @@
 package middleware
 
 import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/andybalholm/brotli"
 	"github.com/goanywhere/rex"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -15,7 +23,7 @@ func TestCompress(t *testing.T) {
 	app := rex.New()
 	app.Use(Compress)
 	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
-		io.WriteString(w, "app")
+		io.WriteString(w, strings.Repeat("app", 1024))
 	})
 
 	Convey("rex.middleware.Compress", t, func() {
@@ -26,5 +34,174 @@ func TestCompress(t *testing.T) {
 		app.ServeHTTP(response, request)
 
 		So(response.Header().Get("Content-Encoding"), ShouldEqual, "gzip")
+		So(response.Header().Get("Content-Length"), ShouldEqual, strconv.Itoa(response.Body.Len()))
+	})
+}
+
+func TestCompressBrotli(t *testing.T) {
+	app := rex.New()
+	app.Use(Compress)
+	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("app", 1024))
+	})
+
+	Convey("rex.middleware.Compress (Brotli)", t, func() {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Accept-Encoding", "gzip, deflate, br")
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+
+		So(response.Header().Get("Content-Encoding"), ShouldEqual, "br")
+		body, err := ioutil.ReadAll(brotli.NewReader(response.Body))
+		So(err, ShouldBeNil)
+		So(string(body), ShouldEqual, strings.Repeat("app", 1024))
+	})
+}
+
+func TestCompressWithOptions(t *testing.T) {
+	app := rex.New()
+	app.Use(CompressWithOptions(CompressOptions{MinSize: 64}))
+	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "app")
+	})
+	app.Get("/chunks", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		for index := 0; index < 32; index++ {
+			io.WriteString(w, "app")
+		}
+	})
+
+	Convey("rex.middleware.CompressWithOptions", t, func() {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Accept-Encoding", "gzip")
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+
+		So(response.Header().Get("Content-Encoding"), ShouldBeEmpty)
+		So(response.Body.String(), ShouldEqual, "app")
+
+		request, _ = http.NewRequest("GET", "/chunks", nil)
+		request.Header.Set("Accept-Encoding", "gzip")
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+
+		So(response.Code, ShouldEqual, http.StatusAccepted)
+		So(response.Header().Get("Content-Encoding"), ShouldEqual, "gzip")
+		So(response.Header().Get("Content-Length"), ShouldBeEmpty)
+		reader, err := gzip.NewReader(response.Body)
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(reader)
+		So(string(body), ShouldEqual, strings.Repeat("app", 32))
+	})
+}
+
+func TestCompressLevel(t *testing.T) {
+	Convey("rex.middleware.CompressLevel", t, func() {
+		for _, level := range []int{flate.BestSpeed, flate.BestCompression, 42} {
+			app := rex.New()
+			app.Use(CompressLevel(level))
+			app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, strings.Repeat("app", 1024))
+			})
+
+			request, _ := http.NewRequest("GET", "/", nil)
+			request.Header.Set("Accept-Encoding", "deflate")
+			response := httptest.NewRecorder()
+
+			app.ServeHTTP(response, request)
+
+			So(response.Header().Get("Content-Encoding"), ShouldEqual, "deflate")
+			body, err := ioutil.ReadAll(flate.NewReader(response.Body))
+			So(err, ShouldBeNil)
+			So(string(body), ShouldEqual, strings.Repeat("app", 1024))
+		}
+	})
+}
+
+func TestCompressTypes(t *testing.T) {
+	app := rex.New()
+	app.Use(CompressTypes("application/vnd.api+json", "image/*"))
+	app.Get("/{mimetype:.+}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Path[1:])
+		io.WriteString(w, strings.Repeat("app", 1024))
+	})
+
+	Convey("rex.middleware.CompressTypes", t, func() {
+		for mimetype, encoding := range map[string]string{
+			"application/vnd.api+json": "gzip",
+			"image/svg+xml":            "gzip",
+			"text/html":                "",
+			"application/json":         "",
+		} {
+			request, _ := http.NewRequest("GET", "/"+mimetype, nil)
+			request.Header.Set("Accept-Encoding", "gzip")
+			response := httptest.NewRecorder()
+
+			app.ServeHTTP(response, request)
+			So(response.Header().Get("Content-Encoding"), ShouldEqual, encoding)
+		}
+	})
+}
+
+func TestCompressFlush(t *testing.T) {
+	var next = make(chan bool)
+	app := rex.New()
+	app.Use(CompressWithOptions(CompressOptions{MinSize: 1}))
+	app.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: one\n\n")
+		w.(http.Flusher).Flush()
+		<-next
+		io.WriteString(w, "data: two\n\n")
+	})
+	server := httptest.NewServer(app)
+	defer server.Close()
+
+	Convey("rex.middleware.Compress (Flush)", t, func() {
+		request, _ := http.NewRequest("GET", server.URL+"/events", nil)
+		request.Header.Set("Accept-Encoding", "gzip")
+		response, err := http.DefaultClient.Do(request)
+		So(err, ShouldBeNil)
+		defer response.Body.Close()
+		So(response.Header.Get("Content-Encoding"), ShouldEqual, "gzip")
+
+		// the first event must arrive before the handler finishes.
+		events := make(chan string)
+		reader, err := gzip.NewReader(response.Body)
+		So(err, ShouldBeNil)
+		lines := bufio.NewReader(reader)
+		go func() {
+			line, _ := lines.ReadString('\n')
+			events <- line
+		}()
+		select {
+		case event := <-events:
+			So(event, ShouldEqual, "data: one\n")
+		case <-time.After(time.Second):
+			So("event", ShouldEqual, "timeout")
+		}
+		close(next)
+
+		rest, _ := ioutil.ReadAll(lines)
+		So(string(rest), ShouldEqual, "\ndata: two\n\n")
+	})
+}
+
+func TestAcceptEncodings(t *testing.T) {
+	Convey("rex.middleware.compressor.acceptEncodings", t, func() {
+		compressor := new(compressor)
+		request, _ := http.NewRequest("GET", "/", nil)
+
+		request.Header.Set("Accept-Encoding", "gzip, br")
+		So(compressor.acceptEncodings(request), ShouldResemble, []string{"br", "gzip"})
+
+		request.Header.Set("Accept-Encoding", "br;q=0.5, gzip;q=1.0, deflate;q=0")
+		So(compressor.acceptEncodings(request), ShouldResemble, []string{"gzip", "br"})
+
+		request.Header.Set("Accept-Encoding", "identity")
+		So(compressor.acceptEncodings(request), ShouldBeEmpty)
 	})
 }
@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const errMultipartSize = "Multipart request body is too large"
+
+type spool struct {
+	*os.File
+}
+
+// Close closes & removes the spooled request body.
+func (self *spool) Close() error {
+	defer os.Remove(self.Name())
+	return self.File.Close()
+}
+
+// MultipartOptions configures the limits of the multipart requests.
+type MultipartOptions struct {
+	// Fields is the maximum number of form fields, non-positive values are not checked.
+	Fields int
+	// Files is the maximum number of file parts, non-positive values are not checked.
+	Files int
+	// MaxBytes is the maximum size of the request body, larger ones are rejected
+	// with 413, e.g. 32 << 20 for 32MB. Non-positive values are not checked.
+	MaxBytes int64
+}
+
+// counter counts the bytes read from the underlying reader.
+type counter struct {
+	io.Reader
+	count int64
+}
+
+func (self *counter) Read(data []byte) (int, error) {
+	n, err := self.Reader.Read(data)
+	self.count += int64(n)
+	return n, err
+}
+
+// MultipartLimit rejects multipart requests with more than the given number
+// of form fields or file parts with 400, guarding against multipart bombs
+// (huge numbers of tiny parts). Non-positive limits are not checked. The
+// body is spooled to a temporary file while counting, so the handler can
+// still parse the form as usual. The size of the body is not limited, use
+// MultipartLimitWithOptions with MaxBytes to reject the large ones as well.
+func MultipartLimit(fields, files int) func(http.Handler) http.Handler {
+	return MultipartLimitWithOptions(MultipartOptions{Fields: fields, Files: files})
+}
+
+// MultipartLimitWithOptions creates the MultipartLimit middleware with the given options.
+func MultipartLimitWithOptions(options MultipartOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mimetype, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if !strings.HasPrefix(mimetype, "multipart/") || params["boundary"] == "" || r.Body == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if options.MaxBytes > 0 && r.ContentLength > options.MaxBytes {
+				http.Error(w, errMultipartSize, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			file, err := ioutil.TempFile("", "rex-multipart")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			body := &spool{file}
+			defer body.Close()
+
+			// http.MaxBytesReader reads one more byte than the limit to detect the excess.
+			var source = &counter{Reader: r.Body}
+			var raw io.Reader = source
+			if options.MaxBytes > 0 {
+				raw = http.MaxBytesReader(w, ioutil.NopCloser(source), options.MaxBytes)
+			}
+			var exceeded = func() bool {
+				return options.MaxBytes > 0 && source.count > options.MaxBytes
+			}
+
+			// count the parts while spooling the raw body for the handler.
+			var tee = io.TeeReader(raw, file)
+			var reader = multipart.NewReader(tee, params["boundary"])
+
+			var nfields, nfiles int
+			for {
+				part, err := reader.NextPart()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					if exceeded() {
+						http.Error(w, errMultipartSize, http.StatusRequestEntityTooLarge)
+					} else {
+						http.Error(w, err.Error(), http.StatusBadRequest)
+					}
+					return
+				}
+				if part.FileName() == "" {
+					nfields++
+				} else {
+					nfiles++
+				}
+				if (options.Fields > 0 && nfields > options.Fields) || (options.Files > 0 && nfiles > options.Files) {
+					http.Error(w, "Too many multipart form parts", http.StatusBadRequest)
+					return
+				}
+				io.Copy(ioutil.Discard, part)
+			}
+
+			// spool the epilogue, if any.
+			io.Copy(ioutil.Discard, tee)
+			if exceeded() {
+				http.Error(w, errMultipartSize, http.StatusRequestEntityTooLarge)
+				return
+			}
+			if _, err = file.Seek(0, io.SeekStart); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			r.Body = body
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goanywhere/rex"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMultipartLimit(t *testing.T) {
+	app := rex.New()
+	app.Use(MultipartLimit(3, 1))
+	app.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			io.WriteString(w, r.FormValue("field0"))
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	form := func(fields, files int) *http.Request {
+		var body = new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		for index := 0; index < fields; index++ {
+			writer.WriteField(fmt.Sprintf("field%d", index), "rex")
+		}
+		for index := 0; index < files; index++ {
+			part, _ := writer.CreateFormFile(fmt.Sprintf("file%d", index), "file.txt")
+			io.WriteString(part, "rex")
+		}
+		writer.Close()
+		request, _ := http.NewRequest("POST", "/", body)
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		return request
+	}
+
+	Convey("rex.middleware.MultipartLimit", t, func() {
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, form(3, 1))
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Body.String(), ShouldEqual, "rex")
+
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, form(100, 0))
+		So(response.Code, ShouldEqual, http.StatusBadRequest)
+
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, form(1, 2))
+		So(response.Code, ShouldEqual, http.StatusBadRequest)
+	})
+}
+
+func TestMultipartLimitWithOptions(t *testing.T) {
+	app := rex.New()
+	app.Use(MultipartLimitWithOptions(MultipartOptions{MaxBytes: 1024}))
+	app.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			io.WriteString(w, r.FormValue("field"))
+		} else {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+
+	form := func(size int) (*bytes.Buffer, string) {
+		var body = new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		writer.WriteField("field", "rex")
+		part, _ := writer.CreateFormFile("file", "file.txt")
+		part.Write(bytes.Repeat([]byte("x"), size))
+		writer.Close()
+		return body, writer.FormDataContentType()
+	}
+
+	Convey("rex.middleware.MultipartLimitWithOptions", t, func() {
+		body, mimetype := form(100)
+		request, _ := http.NewRequest("POST", "/", body)
+		request.Header.Set("Content-Type", mimetype)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Body.String(), ShouldEqual, "rex")
+
+		body, mimetype = form(4096)
+		request, _ = http.NewRequest("POST", "/", body)
+		request.Header.Set("Content-Type", mimetype)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+
+		// unknown length (e.g. chunked) bodies are limited while spooling.
+		body, mimetype = form(4096)
+		request, _ = http.NewRequest("POST", "/", ioutil.NopCloser(body))
+		request.Header.Set("Content-Type", mimetype)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusRequestEntityTooLarge)
+	})
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+const (
+	overrideHeaderName = "X-HTTP-Method-Override"
+	overrideFieldName  = "_method"
+)
+
+var overrideMethods = map[string]bool{"PUT": true, "PATCH": true, "DELETE": true}
+
+// MethodOverride rewrites the method of POST requests to the one given by the
+// X-HTTP-Method-Override header or the "_method" form field, so HTML forms can
+// reach the PUT/PATCH/DELETE routes. Other target methods are ignored.
+// NOTE use it after XSRF, so the overridden requests are still verified as POST.
+func MethodOverride(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			method := r.Header.Get(overrideHeaderName)
+			if method == "" {
+				method = r.PostFormValue(overrideFieldName)
+			}
+			if method = strings.ToUpper(method); overrideMethods[method] {
+				r.Method = method
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
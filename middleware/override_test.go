@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/goanywhere/rex"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMethodOverride(t *testing.T) {
+	app := rex.New()
+	app.Use(MethodOverride)
+	app.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "post")
+	})
+	app.Put("/users", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "put:"+r.FormValue("name"))
+	})
+	app.Delete("/users", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "delete")
+	})
+
+	Convey("rex.middleware.MethodOverride", t, func() {
+		values := url.Values{"_method": {"put"}, "name": {"rex"}}
+		request, _ := http.NewRequest("POST", "/users", strings.NewReader(values.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Body.String(), ShouldEqual, "put:rex")
+
+		request, _ = http.NewRequest("POST", "/users", nil)
+		request.Header.Set("X-HTTP-Method-Override", "DELETE")
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Body.String(), ShouldEqual, "delete")
+
+		request, _ = http.NewRequest("POST", "/users", nil)
+		request.Header.Set("X-HTTP-Method-Override", "GET")
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Body.String(), ShouldEqual, "post")
+	})
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import "net/http"
+
+// RequireHeaders rejects the requests missing any of the given headers
+// (e.g. X-Api-Key or X-Tenant-ID) with 400 before the handler runs.
+func RequireHeaders(names ...string) func(http.Handler) http.Handler {
+	return RequireHeadersWith(nil, names...)
+}
+
+// RequireHeadersWith rejects the requests missing any of the given headers, or
+// whose header value is not accepted by the given predicate, with 400.
+func RequireHeadersWith(valid func(name, value string) bool, names ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, name := range names {
+				value := r.Header.Get(name)
+				if value == "" {
+					http.Error(w, "Missing required header: "+name, http.StatusBadRequest)
+					return
+				}
+				if valid != nil && !valid(name, value) {
+					http.Error(w, "Invalid header: "+name, http.StatusBadRequest)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/goanywhere/rex"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestRequireHeaders(t *testing.T) {
+	app := rex.New()
+	app.Use(RequireHeaders("X-Api-Key", "X-Tenant-ID"))
+	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "app")
+	})
+
+	Convey("rex.middleware.RequireHeaders", t, func() {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("X-Api-Key", "secret")
+		request.Header.Set("X-Tenant-ID", "rex")
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Body.String(), ShouldEqual, "app")
+
+		request.Header.Del("X-Tenant-ID")
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusBadRequest)
+		So(response.Body.String(), ShouldNotContainSubstring, "app")
+	})
+}
+
+func TestRequireHeadersWith(t *testing.T) {
+	app := rex.New()
+	app.Use(RequireHeadersWith(func(name, value string) bool {
+		return strings.HasPrefix(value, "key-")
+	}, "X-Api-Key"))
+	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "app")
+	})
+
+	Convey("rex.middleware.RequireHeadersWith", t, func() {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("X-Api-Key", "key-rex")
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+
+		request.Header.Set("X-Api-Key", "rex")
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goanywhere/rex"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSecure(t *testing.T) {
+	app := rex.New()
+	app.Use(Secure)
+	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "app")
+	})
+
+	Convey("rex.middleware.Secure", t, func() {
+		request, _ := http.NewRequest("GET", "https://example.com/", nil)
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+
+		header := response.Header()
+		So(header.Get("Strict-Transport-Security"), ShouldEqual, "max-age=31536000; includeSubDomains")
+		So(header.Get("X-Content-Type-Options"), ShouldEqual, "nosniff")
+		So(header.Get("X-Frame-Options"), ShouldEqual, "SAMEORIGIN")
+		So(header.Get("Content-Security-Policy"), ShouldBeEmpty)
+
+		request, _ = http.NewRequest("GET", "http://example.com/", nil)
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Header().Get("Strict-Transport-Security"), ShouldBeEmpty)
+	})
+}
+
+func TestSecureWithOptions(t *testing.T) {
+	app := rex.New()
+	app.Use(SecureWithOptions(SecureOptions{
+		STSSeconds:            600,
+		FrameOptions:          "DENY",
+		ContentSecurityPolicy: "default-src 'self'",
+	}))
+	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "app")
+	})
+
+	Convey("rex.middleware.SecureWithOptions", t, func() {
+		request, _ := http.NewRequest("GET", "https://example.com/", nil)
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+
+		header := response.Header()
+		So(header.Get("Strict-Transport-Security"), ShouldEqual, "max-age=600")
+		So(header.Get("X-Content-Type-Options"), ShouldBeEmpty)
+		So(header.Get("X-Frame-Options"), ShouldEqual, "DENY")
+		So(header.Get("Content-Security-Policy"), ShouldEqual, "default-src 'self'")
+	})
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TrailingSlash canonicalizes the paths of GET/HEAD requests with 301 redirects,
+// i.e. "/path" redirects to "/path/" if trailing is true, or "/path/" to "/path"
+// otherwise, the root path "/" is left as it is. It runs before routing, so
+// server.StrictSlash is not involved for the canonicalized paths.
+func TrailingSlash(trailing bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (r.Method == "GET" || r.Method == "HEAD") && r.URL.Path != "/" {
+				// leading slashes are collapsed, "//evil.com/" must never redirect to "//evil.com".
+				var path = "/" + strings.TrimLeft(r.URL.Path, "/")
+				if trailing && !strings.HasSuffix(path, "/") {
+					path += "/"
+				} else if !trailing && strings.HasSuffix(path, "/") {
+					path = strings.TrimRight(path, "/")
+					if path == "" {
+						path = "/"
+					}
+				}
+				if path != r.URL.Path {
+					location := *r.URL
+					location.Path = path
+					location.RawPath = ""
+					http.Redirect(w, r, location.RequestURI(), http.StatusMovedPermanently)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
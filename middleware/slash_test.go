@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goanywhere/rex"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTrailingSlash(t *testing.T) {
+	Convey("rex.middleware.TrailingSlash", t, func() {
+		app := rex.New()
+		app.Use(TrailingSlash(false))
+		app.Get("/users", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "users")
+		})
+
+		request, _ := http.NewRequest("GET", "/users/?page=2", nil)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusMovedPermanently)
+		So(response.Header().Get("Location"), ShouldEqual, "/users?page=2")
+
+		request, _ = http.NewRequest("GET", "/users", nil)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Body.String(), ShouldEqual, "users")
+
+		// no open redirect to other hosts.
+		request, _ = http.NewRequest("GET", "http://localhost//evil.com/", nil)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusMovedPermanently)
+		So(response.Header().Get("Location"), ShouldEqual, "/evil.com")
+
+		app = rex.New()
+		app.Use(TrailingSlash(true))
+		app.Get("/users/", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "users")
+		})
+
+		request, _ = http.NewRequest("GET", "/users", nil)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusMovedPermanently)
+		So(response.Header().Get("Location"), ShouldEqual, "/users/")
+
+		request, _ = http.NewRequest("POST", "/users", nil)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldNotEqual, http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+type throttle struct {
+	io.ReadCloser
+	rate  int
+	read  int64
+	start time.Time
+}
+
+// Read reads the request body no faster than the configured bytes per second.
+func (self *throttle) Read(data []byte) (size int, err error) {
+	if self.start.IsZero() {
+		self.start = time.Now()
+	}
+	if len(data) > self.rate {
+		data = data[:self.rate]
+	}
+	size, err = self.ReadCloser.Read(data)
+	self.read += int64(size)
+
+	// sleep until the elapsed time matches the bytes consumed so far.
+	if wait := duration(self.read, self.rate) - time.Since(self.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	return
+}
+
+// duration returns the time to read the given bytes at the rate of bytes per second,
+// whole seconds & the remainder are computed apart to avoid overflowing on large bodies.
+func duration(read int64, rate int) time.Duration {
+	var bytesPerSec = int64(rate)
+	return time.Duration(read/bytesPerSec)*time.Second +
+		time.Duration(read%bytesPerSec*int64(time.Second)/bytesPerSec)
+}
+
+// ThrottleUpload limits the rate of reading the request body of each
+// request to the given bytes per second, so fast large uploads can not
+// saturate the bandwidth or memory of the server.
+func ThrottleUpload(bytesPerSec int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if bytesPerSec > 0 && r.Body != nil {
+				r.Body = &throttle{ReadCloser: r.Body, rate: bytesPerSec}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
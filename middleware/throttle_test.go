@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goanywhere/rex"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestThrottleUpload(t *testing.T) {
+	var size int
+	app := rex.New()
+	app.Use(ThrottleUpload(8192))
+	app.Post("/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		size = len(body)
+	})
+
+	Convey("rex.middleware.ThrottleUpload", t, func() {
+		request, _ := http.NewRequest("POST", "/", bytes.NewReader(make([]byte, 4096)))
+		response := httptest.NewRecorder()
+
+		start := time.Now()
+		app.ServeHTTP(response, request)
+
+		So(size, ShouldEqual, 4096)
+		So(time.Since(start), ShouldBeGreaterThanOrEqualTo, 400*time.Millisecond)
+	})
+}
+
+func TestThrottleDuration(t *testing.T) {
+	Convey("rex.middleware.ThrottleUpload (duration)", t, func() {
+		So(duration(4096, 8192), ShouldEqual, 500*time.Millisecond)
+		So(duration(12288, 8192), ShouldEqual, 1500*time.Millisecond)
+		// 10GB read through one body no longer overflows int64 nanoseconds.
+		So(duration(10<<30, 1<<20), ShouldEqual, 10240*time.Second)
+	})
+}
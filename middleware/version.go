@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+type versionKey struct{}
+
+// matches vendor media types like application/vnd.myapp.v2+json.
+var regexMediaVersion = regexp.MustCompile(`vnd\.[^;,]+\.v([0-9][0-9.]*)(\+[a-z]+)?`)
+
+// APIVersion parses the requested API version from the given custom header
+// (e.g. X-API-Version: 2), or the vendor media type in the Accept header
+// (e.g. application/vnd.myapp.v2+json), falling back to the given default.
+// The version is stored within the request, use APIVersionOf to retrieve it.
+func APIVersion(header, version string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var value = version
+			if header != "" && r.Header.Get(header) != "" {
+				value = strings.TrimPrefix(strings.TrimSpace(r.Header.Get(header)), "v")
+			} else if matches := regexMediaVersion.FindStringSubmatch(r.Header.Get("Accept")); matches != nil {
+				value = matches[1]
+			}
+			// the version depends on the custom header (even when absent) as well.
+			if header != "" {
+				w.Header().Add("Vary", http.CanonicalHeaderKey(header))
+			}
+			w.Header().Add("Vary", "Accept")
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), versionKey{}, value)))
+		})
+	}
+}
+
+// APIVersionOf returns the API version parsed by the APIVersion middleware, if any.
+func APIVersionOf(r *http.Request) string {
+	version, _ := r.Context().Value(versionKey{}).(string)
+	return version
+}
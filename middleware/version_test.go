@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goanywhere/rex"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAPIVersion(t *testing.T) {
+	app := rex.New()
+	app.Use(APIVersion("X-API-Version", "1"))
+	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, APIVersionOf(r))
+	})
+
+	Convey("rex.middleware.APIVersion", t, func() {
+		for accept, version := range map[string]string{
+			"":                              "1",
+			"application/json":              "1",
+			"application/vnd.myapp.v2+json": "2",
+			"application/vnd.myapp.v3":      "3",
+			"text/html, application/vnd.a.v2.1+xml;q=0.9": "2.1",
+		} {
+			request, _ := http.NewRequest("GET", "/", nil)
+			request.Header.Set("Accept", accept)
+			response := httptest.NewRecorder()
+
+			app.ServeHTTP(response, request)
+			So(response.Body.String(), ShouldEqual, version)
+		}
+
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Accept", "application/vnd.myapp.v2+json")
+		request.Header.Set("X-API-Version", "v4")
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Body.String(), ShouldEqual, "4")
+		So(response.Header()["Vary"], ShouldResemble, []string{"X-Api-Version", "Accept"})
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/goanywhere/crypto"
@@ -30,14 +31,40 @@ var (
 	errXSRFReferer = "Referer URL is missing from the request or the value was malformed."
 	errXSRFToken   = "Invalid XSRF tokens"
 
+	sameSites = map[string]string{"strict": "Strict", "lax": "Lax", "none": "None"}
+
 	xsrfPattern   = regexp.MustCompile("[^0-9a-zA-Z-_]")
 	unsafeMethods = regexp.MustCompile("^(DELETE|POST|PUT)$")
 )
 
+// XSRFOptions configures the attributes of the XSRF token cookie.
+type XSRFOptions struct {
+	// Name of the cookie, defaults to "xsrf".
+	Name string
+	// Path of the cookie, defaults to "/".
+	Path string
+	// Domain of the cookie, e.g. ".example.com" to share it across subdomains.
+	Domain string
+	// Secure forces the Secure attribute, which is always set for HTTPS requests.
+	Secure bool
+	// SameSite attribute of the cookie: "Strict", "Lax" or "None" (which forces Secure,
+	// as browsers drop the non-secure ones), any other values panic on setup.
+	SameSite string
+	// MaxAge of the cookie in seconds, defaults to a year.
+	MaxAge int
+	// Exempt skips the token verification for the requests whose URL path
+	// matches, e.g. regexp.MustCompile(`^/(webhooks|api)/`). Exempted requests
+	// still get the token cookie. NOTE exempted endpoints are open to forged
+	// cross-site requests, only exempt those authenticated by other means
+	// (e.g. webhook signatures or API tokens), never cookie-based sessions.
+	Exempt *regexp.Regexp
+}
+
 type xsrf struct {
 	*http.Request
 	http.ResponseWriter
-	token string
+	options *XSRFOptions
+	token   string
 }
 
 // See http://en.wikipedia.org/wiki/Same-origin_policy
@@ -106,7 +133,7 @@ func (self *xsrf) checkToken(token string) bool {
 func (self *xsrf) generate() {
 	// Ensure we have XSRF token in the cookie first.
 	var token string
-	if cookie, err := self.Request.Cookie(xsrfCookieName); err == nil {
+	if cookie, err := self.Request.Cookie(self.options.Name); err == nil {
 		if cookie.Value != "" {
 			token = cookie.Value
 		}
@@ -122,12 +149,20 @@ func (self *xsrf) generate() {
 		// The max-age directive takes priority over Expires.
 		//	http://www.w3.org/Protocols/rfc2616/rfc2616-sec13.html
 		cookie := new(http.Cookie)
-		cookie.Name = xsrfCookieName
+		cookie.Name = self.options.Name
 		cookie.Value = token
-		cookie.MaxAge = xsrfMaxAge
-		cookie.Path = "/"
+		cookie.MaxAge = self.options.MaxAge
+		cookie.Path = self.options.Path
+		cookie.Domain = self.options.Domain
+		cookie.Secure = self.options.Secure || self.options.SameSite == "None" ||
+			self.Request.TLS != nil || self.Request.URL.Scheme == "https"
 		cookie.HttpOnly = true
-		http.SetCookie(self.ResponseWriter, cookie)
+		if value := cookie.String(); value != "" {
+			if self.options.SameSite != "" {
+				value += "; SameSite=" + self.options.SameSite
+			}
+			self.ResponseWriter.Header().Add("Set-Cookie", value)
+		}
 	}
 	self.ResponseWriter.Header()[xsrfHeaderName] = []string{token}
 	self.token = token
@@ -135,28 +170,56 @@ func (self *xsrf) generate() {
 
 // XSRF serves as Cross-Site Request Forgery protection middleware.
 func XSRF(next http.Handler) http.Handler {
-	fn := func(w http.ResponseWriter, r *http.Request) {
-		x := new(xsrf)
-		x.Request = r
-		x.ResponseWriter = w
-		x.generate()
-
-		if unsafeMethods.MatchString(r.Method) {
-			// Ensure the URL came for "Referer" under HTTPS.
-			if !x.checkOrigin() {
-				http.Error(w, errXSRFReferer, http.StatusForbidden)
-			}
+	return XSRFWithOptions(XSRFOptions{})(next)
+}
 
-			// length => bytes => issue time checkpoints.
-			if !x.checkToken(x.token) {
-				http.Error(w, errXSRFToken, http.StatusForbidden)
-			}
+// XSRFWithOptions creates the Cross-Site Request Forgery protection
+// middleware using the given token cookie options.
+func XSRFWithOptions(options XSRFOptions) func(http.Handler) http.Handler {
+	if options.Name == "" {
+		options.Name = xsrfCookieName
+	}
+	if options.Path == "" {
+		options.Path = "/"
+	}
+	if options.MaxAge == 0 {
+		options.MaxAge = xsrfMaxAge
+	}
+	if options.SameSite != "" {
+		if value, exists := sameSites[strings.ToLower(options.SameSite)]; exists {
+			options.SameSite = value
+		} else {
+			panic("Invalid XSRF cookie SameSite attribute: " + strconv.Quote(options.SameSite))
 		}
+	}
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			x := new(xsrf)
+			x.Request = r
+			x.ResponseWriter = w
+			x.options = &options
+			x.generate()
+
+			exempted := options.Exempt != nil && options.Exempt.MatchString(r.URL.Path)
+			if unsafeMethods.MatchString(r.Method) && !exempted {
+				// Ensure the URL came for "Referer" under HTTPS.
+				if !x.checkOrigin() {
+					http.Error(w, errXSRFReferer, http.StatusForbidden)
+					return
+				}
+
+				// length => bytes => issue time checkpoints.
+				if !x.checkToken(x.token) {
+					http.Error(w, errXSRFToken, http.StatusForbidden)
+					return
+				}
+			}
 
-		// ensure browser will invalidate the cached XSRF token.
-		w.Header().Add("Vary", "Cookie")
+			// ensure browser will invalidate the cached XSRF token.
+			w.Header().Add("Vary", "Cookie")
 
-		next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
 	}
-	return http.HandlerFunc(fn)
 }
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/goanywhere/rex"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestXSRF(t *testing.T) {
+	var invoked int
+	app := rex.New()
+	app.Use(XSRF)
+	app.Any("/", func(w http.ResponseWriter, r *http.Request) {
+		invoked++
+		io.WriteString(w, "app")
+	})
+
+	Convey("rex.middleware.XSRF", t, func() {
+		request, _ := http.NewRequest("GET", "/", nil)
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(invoked, ShouldEqual, 1)
+
+		So(response.Header()[xsrfHeaderName], ShouldHaveLength, 1)
+		token := response.Header()[xsrfHeaderName][0]
+
+		// valid token
+		request, _ = http.NewRequest("POST", "/", nil)
+		request.AddCookie(&http.Cookie{Name: xsrfCookieName, Value: token})
+		request.Header.Set(xsrfFieldName, token)
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(invoked, ShouldEqual, 2)
+
+		// invalid token
+		request, _ = http.NewRequest("POST", "/", nil)
+		request.AddCookie(&http.Cookie{Name: xsrfCookieName, Value: token})
+		request.Header.Set(xsrfFieldName, "invalid")
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusForbidden)
+		So(response.Body.String(), ShouldEqual, errXSRFToken+"\n")
+		So(invoked, ShouldEqual, 2)
+	})
+}
+
+func TestXSRFWithOptions(t *testing.T) {
+	app := rex.New()
+	app.Use(XSRFWithOptions(XSRFOptions{
+		Name:     "csrf",
+		Path:     "/app",
+		Domain:   "example.com",
+		SameSite: "Strict",
+		MaxAge:   3600,
+	}))
+	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "app")
+	})
+
+	Convey("rex.middleware.XSRFWithOptions", t, func() {
+		request, _ := http.NewRequest("GET", "https://example.com/", nil)
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		cookie := response.Header().Get("Set-Cookie")
+		So(cookie, ShouldStartWith, "csrf=")
+		So(cookie, ShouldContainSubstring, "Path=/app")
+		So(cookie, ShouldContainSubstring, "Domain=example.com")
+		So(cookie, ShouldContainSubstring, "Max-Age=3600")
+		So(cookie, ShouldContainSubstring, "Secure")
+		So(cookie, ShouldEndWith, "SameSite=Strict")
+
+		request, _ = http.NewRequest("GET", "http://example.com/", nil)
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Header().Get("Set-Cookie"), ShouldNotContainSubstring, "Secure")
+	})
+}
+
+func TestXSRFSameSite(t *testing.T) {
+	Convey("rex.middleware.XSRFWithOptions SameSite", t, func() {
+		app := rex.New()
+		app.Use(XSRFWithOptions(XSRFOptions{SameSite: "none"}))
+		app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "app")
+		})
+
+		request, _ := http.NewRequest("GET", "http://example.com/", nil)
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		cookie := response.Header().Get("Set-Cookie")
+		So(cookie, ShouldContainSubstring, "Secure")
+		So(cookie, ShouldEndWith, "SameSite=None")
+
+		So(func() { XSRFWithOptions(XSRFOptions{SameSite: "Strict; Domain=evil.com"}) }, ShouldPanic)
+	})
+}
+
+func TestXSRFExempt(t *testing.T) {
+	app := rex.New()
+	app.Use(XSRFWithOptions(XSRFOptions{Exempt: regexp.MustCompile(`^/webhooks/`)}))
+	app.Post("/webhooks/github", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "webhook")
+	})
+	app.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "users")
+	})
+
+	Convey("rex.middleware.XSRFWithOptions (Exempt)", t, func() {
+		request, _ := http.NewRequest("POST", "/webhooks/github", nil)
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Body.String(), ShouldEqual, "webhook")
+		So(response.Header().Get("Set-Cookie"), ShouldStartWith, xsrfCookieName+"=")
+
+		request, _ = http.NewRequest("POST", "/users", nil)
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusForbidden)
+	})
+}
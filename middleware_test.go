@@ -37,3 +37,92 @@ func TestMiddleware(t *testing.T) {
 		So(response.Header().Get("Content-Type"), ShouldEqual, "application/json")
 	})
 }
+
+func TestMiddlewareOrder(t *testing.T) {
+	Convey("rex.middleware order", t, func() {
+		var calls []string
+		module := func(name string) func(http.Handler) http.Handler {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					calls = append(calls, name+":before")
+					next.ServeHTTP(w, r)
+					calls = append(calls, name+":after")
+				})
+			}
+		}
+
+		app := New()
+		app.Use(module("A"))
+		app.Use(module("B"))
+		app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+		})
+
+		request, _ := http.NewRequest("GET", "/", nil)
+		app.ServeHTTP(httptest.NewRecorder(), request)
+		So(calls, ShouldResemble, []string{"A:before", "B:before", "handler", "B:after", "A:after"})
+	})
+}
+
+func BenchmarkMiddleware(b *testing.B) {
+	module := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+		})
+	}
+	app := New()
+	app.Use(module, module, module, module, module)
+	app.Get("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	request, _ := http.NewRequest("GET", "/", nil)
+	response := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		app.ServeHTTP(response, request)
+	}
+}
+
+// modules returns the stack of the given number of pass-through middleware modules.
+func modules(count int) []func(http.Handler) http.Handler {
+	var stack []func(http.Handler) http.Handler
+	for index := 0; index < count; index++ {
+		stack = append(stack, func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+	return stack
+}
+
+// BenchmarkMiddlewareOnce serves the requests via the chain built only once.
+func BenchmarkMiddlewareOnce(b *testing.B) {
+	stack := &middleware{stack: modules(5)}
+
+	request, _ := http.NewRequest("GET", "/", nil)
+	response := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		stack.ServeHTTP(response, request)
+	}
+}
+
+// BenchmarkMiddlewareRebuild serves the requests via the chain rebuilt on each
+// of them, as it used to be before the chain was built with sync.Once.
+func BenchmarkMiddlewareRebuild(b *testing.B) {
+	stack := modules(5)
+
+	request, _ := http.NewRequest("GET", "/", nil)
+	response := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		var next http.Handler = http.DefaultServeMux
+		for index := len(stack) - 1; index >= 0; index-- {
+			next = stack[index](next)
+		}
+		next.ServeHTTP(response, request)
+	}
+}
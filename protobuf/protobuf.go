@@ -0,0 +1,41 @@
+// Package protobuf provides protocol buffers helpers for binary HTTP APIs,
+// kept in its own package so that rex itself doesn't depend on protobuf.
+package protobuf
+
+import (
+	"errors"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// ContentType is the media type of protocol buffers messages.
+const ContentType = "application/protobuf"
+
+// ErrContentType is returned by Bind when the request body is not protocol buffers.
+var ErrContentType = errors.New("Content-Type of the request is not application/protobuf")
+
+// Bind decodes the protocol buffers request body into the given message.
+func Bind(r *http.Request, msg proto.Message) error {
+	mimetype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mimetype != ContentType && mimetype != "application/x-protobuf" {
+		return ErrContentType
+	}
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	return proto.Unmarshal(data, msg)
+}
+
+// Send sends the HTTP response in protocol buffers.
+func Send(w http.ResponseWriter, msg proto.Message) {
+	if data, err := proto.Marshal(msg); err == nil {
+		w.Header().Set("Content-Type", ContentType)
+		w.Write(data)
+	} else {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
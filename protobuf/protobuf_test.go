@@ -0,0 +1,46 @@
+package protobuf
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestProtobuf(t *testing.T) {
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg wrappers.StringValue
+		if err := Bind(r, &msg); err == nil {
+			Send(w, &msg)
+		} else {
+			http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		}
+	})
+
+	Convey("rex.protobuf", t, func() {
+		data, _ := proto.Marshal(&wrappers.StringValue{Value: "rex"})
+
+		request, _ := http.NewRequest("POST", "/", bytes.NewReader(data))
+		request.Header.Set("Content-Type", ContentType)
+		response := httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Header().Get("Content-Type"), ShouldEqual, ContentType)
+
+		var msg wrappers.StringValue
+		So(proto.Unmarshal(response.Body.Bytes(), &msg), ShouldBeNil)
+		So(msg.Value, ShouldEqual, "rex")
+
+		request, _ = http.NewRequest("POST", "/", bytes.NewReader(data))
+		request.Header.Set("Content-Type", "application/json")
+		response = httptest.NewRecorder()
+
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusUnsupportedMediaType)
+	})
+}
@@ -0,0 +1,150 @@
+package rex
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+var privateNetworks []*net.IPNet
+
+// TrustedProxyHeaders are the proxy headers (e.g. Forwarded, X-Forwarded-For,
+// X-Real-IP) trusted to carry the client's information. It is empty by default,
+// since these headers can be spoofed freely when not behind a trusted proxy.
+var TrustedProxyHeaders []string
+
+// Forward is a single element of the Forwarded (RFC 7239) header.
+type Forward struct {
+	By    string
+	For   string
+	Host  string
+	Proto string
+}
+
+// trusted checks if the given proxy header is trusted.
+func trusted(header string) bool {
+	for _, name := range TrustedProxyHeaders {
+		if http.CanonicalHeaderKey(name) == http.CanonicalHeaderKey(header) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseForwarded parses the value of the Forwarded (RFC 7239) header, e.g.
+// `for=192.0.2.43;proto=https, for="[2001:db8:cafe::17]:4711"`, into its
+// elements, ordered from the client to the last proxy.
+func ParseForwarded(value string) (forwards []Forward) {
+	for _, element := range split(value, ',') {
+		var forward Forward
+		for _, pair := range split(element, ';') {
+			units := strings.SplitN(pair, "=", 2)
+			if len(units) != 2 {
+				continue
+			}
+			value := strings.TrimSpace(units[1])
+			if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+				value = strings.Replace(value[1:len(value)-1], `\"`, `"`, -1)
+			}
+			switch strings.ToLower(strings.TrimSpace(units[0])) {
+			case "by":
+				forward.By = value
+			case "for":
+				forward.For = value
+			case "host":
+				forward.Host = value
+			case "proto":
+				forward.Proto = strings.ToLower(value)
+			}
+		}
+		if forward != (Forward{}) {
+			forwards = append(forwards, forward)
+		}
+	}
+	return
+}
+
+// Forwarded returns the parsed Forwarded (RFC 7239) header of the request,
+// only if the header is listed in TrustedProxyHeaders.
+func Forwarded(r *http.Request) []Forward {
+	if !trusted("Forwarded") {
+		return nil
+	}
+	return ParseForwarded(strings.Join(r.Header["Forwarded"], ","))
+}
+
+// split slices the value by the separator outside of quoted strings.
+func split(value string, separator byte) (units []string) {
+	var quoted bool
+	var start int
+	for index := 0; index < len(value); index++ {
+		switch value[index] {
+		case '\\':
+			index++
+		case '"':
+			quoted = !quoted
+		case separator:
+			if !quoted {
+				units = append(units, strings.TrimSpace(value[start:index]))
+				start = index + 1
+			}
+		}
+	}
+	return append(units, strings.TrimSpace(value[start:]))
+}
+
+// ClientIP returns the IP address of the client, using the trusted proxy headers
+// in the order of Forwarded, X-Forwarded-For & X-Real-IP, where the left-most
+// public address is taken, and finally the RemoteAddr of the request.
+func ClientIP(r *http.Request) string {
+	for _, forward := range Forwarded(r) {
+		if ip := parseIP(forward.For); ip != nil && !private(ip) {
+			return ip.String()
+		}
+	}
+	if trusted("X-Forwarded-For") {
+		for _, value := range strings.Split(strings.Join(r.Header["X-Forwarded-For"], ","), ",") {
+			if ip := parseIP(value); ip != nil && !private(ip) {
+				return ip.String()
+			}
+		}
+	}
+	if trusted("X-Real-IP") {
+		if ip := parseIP(r.Header.Get("X-Real-IP")); ip != nil {
+			return ip.String()
+		}
+	}
+	if ip := parseIP(r.RemoteAddr); ip != nil {
+		return ip.String()
+	}
+	return r.RemoteAddr
+}
+
+// parseIP parses the IP address with optional port, e.g. "192.0.2.43:80" or "[2001:db8::17]:4711".
+func parseIP(value string) net.IP {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	return net.ParseIP(strings.Trim(value, "[]"))
+}
+
+// private checks if the IP address is a loopback, link-local or private network address.
+func private(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	for _, cidr := range []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10", "fc00::/7"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		privateNetworks = append(privateNetworks, network)
+	}
+}
@@ -0,0 +1,61 @@
+package rex
+
+import (
+	"net/http"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParseForwarded(t *testing.T) {
+	Convey("rex.ParseForwarded", t, func() {
+		forwards := ParseForwarded(`for=192.0.2.43;proto=HTTPS;host=example.com, for="[2001:db8:cafe::17]:4711";by=203.0.113.60, for="_hidden;x"`)
+		So(forwards, ShouldResemble, []Forward{
+			{For: "192.0.2.43", Proto: "https", Host: "example.com"},
+			{For: "[2001:db8:cafe::17]:4711", By: "203.0.113.60"},
+			{For: "_hidden;x"},
+		})
+		So(ParseForwarded(""), ShouldBeEmpty)
+	})
+}
+
+func TestForwarded(t *testing.T) {
+	Convey("rex.Forwarded", t, func() {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Add("Forwarded", "for=192.0.2.43")
+		request.Header.Add("Forwarded", "for=198.51.100.17;proto=https")
+		So(Forwarded(request), ShouldBeNil)
+
+		TrustedProxyHeaders = []string{"forwarded"}
+		defer func() { TrustedProxyHeaders = nil }()
+		So(Forwarded(request), ShouldResemble, []Forward{
+			{For: "192.0.2.43"},
+			{For: "198.51.100.17", Proto: "https"},
+		})
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	Convey("rex.ClientIP", t, func() {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.RemoteAddr = "10.0.0.2:51234"
+		request.Header.Set("X-Forwarded-For", "10.1.1.1, 203.0.113.7, 198.51.100.17")
+		request.Header.Set("X-Real-IP", "198.51.100.1")
+		So(ClientIP(request), ShouldEqual, "10.0.0.2")
+
+		defer func() { TrustedProxyHeaders = nil }()
+		TrustedProxyHeaders = []string{"X-Real-IP"}
+		So(ClientIP(request), ShouldEqual, "198.51.100.1")
+
+		TrustedProxyHeaders = []string{"X-Forwarded-For", "X-Real-IP"}
+		So(ClientIP(request), ShouldEqual, "203.0.113.7")
+
+		request.Header.Set("Forwarded", `for="[2001:db8:cafe::17]:4711"`)
+		TrustedProxyHeaders = []string{"Forwarded", "X-Forwarded-For"}
+		So(ClientIP(request), ShouldEqual, "2001:db8:cafe::17")
+
+		request.RemoteAddr = "[::1]:8080"
+		TrustedProxyHeaders = nil
+		So(ClientIP(request), ShouldEqual, "::1")
+	})
+}
@@ -0,0 +1,149 @@
+package rex
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxUploadSize is the maximum size in bytes of a single uploaded file.
+var MaxUploadSize int64 = 32 << 20
+
+// MaxMemory is the maximum bytes of the multipart form kept in memory,
+// the remainder of the files are stored on disk in temporary files.
+var MaxMemory int64 = 32 << 20
+
+var (
+	ErrUploadMissing  = errors.New("Uploaded file is missing from the request")
+	ErrUploadTooLarge = errors.New("Uploaded file exceeds the maximum upload size")
+)
+
+// StreamUpload copies the named multipart file part of the request directly to
+// the given writer (e.g. a disk file or a remote uploader) without buffering the
+// whole file in memory, returning the number of bytes written.
+func StreamUpload(r *http.Request, name string, dst io.Writer) (written int64, err error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return 0, ErrUploadMissing
+		} else if err != nil {
+			return 0, err
+		}
+		if part.FormName() != name || part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		defer part.Close()
+
+		written, err = io.Copy(dst, io.LimitReader(part, MaxUploadSize+1))
+		if err == nil && written > MaxUploadSize {
+			err = ErrUploadTooLarge
+		}
+		return written, err
+	}
+}
+
+// FormFile returns the first file for the named multipart form field of the request,
+// the form is parsed with MaxMemory, e.g. for avatar/document uploads.
+func FormFile(r *http.Request, name string) (multipart.File, *multipart.FileHeader, error) {
+	if r.MultipartForm == nil {
+		if err := r.ParseMultipartForm(MaxMemory); err != nil {
+			return nil, nil, err
+		}
+	}
+	file, header, err := r.FormFile(name)
+	if err == http.ErrMissingFile {
+		err = ErrUploadMissing
+	}
+	return file, header, err
+}
+
+// SaveUploadedFile copies the uploaded file to the given path on disk.
+func SaveUploadedFile(header *multipart.FileHeader, filename string) error {
+	src, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// QueryDefault returns the named query parameter of the request, or the default if absent.
+func QueryDefault(r *http.Request, key, def string) string {
+	if value := r.URL.Query().Get(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// QueryInt returns the named query parameter as int, or the default if absent/malformed.
+func QueryInt(r *http.Request, key string, def int) int {
+	if value, err := strconv.Atoi(r.URL.Query().Get(key)); err == nil {
+		return value
+	}
+	return def
+}
+
+// QueryFloat returns the named query parameter as float64, or the default if absent/malformed.
+func QueryFloat(r *http.Request, key string, def float64) float64 {
+	if value, err := strconv.ParseFloat(r.URL.Query().Get(key), 64); err == nil {
+		return value
+	}
+	return def
+}
+
+// QueryBool returns the named query parameter as bool, or the default if absent/malformed.
+func QueryBool(r *http.Request, key string, def bool) bool {
+	if value, err := strconv.ParseBool(r.URL.Query().Get(key)); err == nil {
+		return value
+	}
+	return def
+}
+
+// Vars returns the route variables of the request, without referring to the server.
+func Vars(r *http.Request) map[string]string {
+	return mux.Vars(r)
+}
+
+// Param returns the named route variable of the request, e.g. "id" of "/users/{id}".
+func Param(r *http.Request, key string) string {
+	return Vars(r)[key]
+}
+
+// Redirect replies to the request with a redirect to the given URL, carrying forward
+// the named query parameters of the current request, e.g. for the post/redirect/get
+// flow, Redirect(w, r, "/signup", http.StatusSeeOther, "next", "ref").
+func Redirect(w http.ResponseWriter, r *http.Request, target string, code int, params ...string) {
+	if len(params) > 0 {
+		if location, err := url.Parse(target); err == nil {
+			query := location.Query()
+			for _, name := range params {
+				if values, exists := r.URL.Query()[name]; exists {
+					query[name] = values
+				}
+			}
+			location.RawQuery = query.Encode()
+			target = location.String()
+		}
+	}
+	http.Redirect(w, r, target, code)
+}
@@ -0,0 +1,113 @@
+package rex
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// upload creates a multipart request with a text field and the given file.
+func upload(name string, content []byte) *http.Request {
+	var body = new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	writer.WriteField("username", "rex")
+	part, _ := writer.CreateFormFile(name, "avatar.png")
+	part.Write(content)
+	writer.Close()
+
+	request, _ := http.NewRequest("POST", "/", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestStreamUpload(t *testing.T) {
+	Convey("rex.StreamUpload", t, func() {
+		var buffer = new(bytes.Buffer)
+		written, err := StreamUpload(upload("avatar", []byte("avatar")), "avatar", buffer)
+		So(err, ShouldBeNil)
+		So(written, ShouldEqual, 6)
+		So(buffer.String(), ShouldEqual, "avatar")
+
+		_, err = StreamUpload(upload("avatar", []byte("avatar")), "document", new(bytes.Buffer))
+		So(err, ShouldEqual, ErrUploadMissing)
+
+		MaxUploadSize = 4
+		defer func() { MaxUploadSize = 32 << 20 }()
+		_, err = StreamUpload(upload("avatar", []byte("avatar")), "avatar", new(bytes.Buffer))
+		So(err, ShouldEqual, ErrUploadTooLarge)
+	})
+}
+
+func TestFormFile(t *testing.T) {
+	Convey("rex.FormFile", t, func() {
+		request := upload("avatar", []byte("avatar"))
+		file, header, err := FormFile(request, "avatar")
+		So(err, ShouldBeNil)
+		So(header.Filename, ShouldEqual, "avatar.png")
+		file.Close()
+		So(request.FormValue("username"), ShouldEqual, "rex")
+
+		filename := filepath.Join(os.TempDir(), "avatar.png")
+		defer os.Remove(filename)
+		So(SaveUploadedFile(header, filename), ShouldBeNil)
+		content, _ := ioutil.ReadFile(filename)
+		So(string(content), ShouldEqual, "avatar")
+
+		_, _, err = FormFile(request, "document")
+		So(err, ShouldEqual, ErrUploadMissing)
+	})
+}
+
+func TestQuery(t *testing.T) {
+	Convey("rex.Query*", t, func() {
+		request, _ := http.NewRequest("GET", "/?page=2&size=x&ratio=0.5&debug=true&sort=name", nil)
+		So(QueryDefault(request, "sort", "id"), ShouldEqual, "name")
+		So(QueryDefault(request, "order", "asc"), ShouldEqual, "asc")
+		So(QueryInt(request, "page", 1), ShouldEqual, 2)
+		So(QueryInt(request, "size", 20), ShouldEqual, 20)
+		So(QueryFloat(request, "ratio", 1), ShouldEqual, 0.5)
+		So(QueryFloat(request, "missing", 1), ShouldEqual, 1)
+		So(QueryBool(request, "debug", false), ShouldBeTrue)
+		So(QueryBool(request, "sort", false), ShouldBeFalse)
+	})
+}
+
+func TestParam(t *testing.T) {
+	Convey("rex.Param", t, func() {
+		app := New()
+		app.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			So(Vars(r), ShouldResemble, map[string]string{"id": "42"})
+			io.WriteString(w, Param(r, "id")+Param(r, "missing"))
+		})
+
+		request, _ := http.NewRequest("GET", "/users/42", nil)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Body.String(), ShouldEqual, "42")
+	})
+}
+
+func TestRedirect(t *testing.T) {
+	Convey("rex.Redirect", t, func() {
+		request, _ := http.NewRequest("POST", "/signup?next=/home&ref=mail&token=secret", nil)
+		response := httptest.NewRecorder()
+
+		Redirect(response, request, "/signup?error=1", http.StatusSeeOther, "next", "ref", "missing")
+		So(response.Code, ShouldEqual, http.StatusSeeOther)
+
+		location, _ := url.Parse(response.Header().Get("Location"))
+		So(location.Path, ShouldEqual, "/signup")
+		So(location.Query(), ShouldResemble, url.Values{
+			"error": {"1"}, "next": {"/home"}, "ref": {"mail"},
+		})
+	})
+}
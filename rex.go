@@ -1,39 +1,164 @@
 package rex
 
 import (
-	"bytes"
 	"encoding/json"
+	"fmt"
+	"mime"
 	"net/http"
 	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/goanywhere/env"
 	"github.com/goanywhere/fs"
 )
 
+var regexCallback = regexp.MustCompile(`^[a-zA-Z_$][0-9a-zA-Z_$]*(\.[a-zA-Z_$][0-9a-zA-Z_$]*)*$`)
+
 // Shortcut for string based map.
 type M map[string]interface{}
 
-// Sends the HTTP response in JSON.
-func Send(w http.ResponseWriter, v interface{}) {
-	var buffer = new(bytes.Buffer)
-	defer func() {
-		buffer.Reset()
-	}()
+// JSONIndent indents the JSON responses sent by Send, e.g. "  " for pretty-printing,
+// two spaces are used in debug mode if not given.
+var JSONIndent string
 
+// Sends the HTTP response in JSON, nil values (including nil maps & slices) are sent as null.
+// The value is encoded directly into the http.ResponseWriter without an
+// intermediate buffer, so errors occurring in the middle of the stream
+// can no longer change the status code that has already been sent.
+func Send(w http.ResponseWriter, v interface{}) {
 	w.Header().Set("Content-type", "application/json; charset=utf-8")
-	if err := json.NewEncoder(buffer).Encode(v); err == nil {
-		w.Write(buffer.Bytes())
+	encoder := json.NewEncoder(w)
+	if indent := JSONIndent; indent != "" || debug {
+		if indent == "" {
+			indent = "  "
+		}
+		encoder.SetIndent("", indent)
+	}
+	if err := encoder.Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// JSONP sends the HTTP response in JSON wrapped by the given callback,
+// e.g. callback({"key": "value"}), for legacy cross-domain clients.
+// Callbacks which are not valid JavaScript identifiers are rejected with 400.
+func JSONP(w http.ResponseWriter, callback string, v interface{}) {
+	if !regexCallback.MatchString(callback) {
+		http.Error(w, "Invalid JSONP callback", http.StatusBadRequest)
+		return
+	}
+	if bytes, err := json.Marshal(v); err == nil {
+		w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		fmt.Fprintf(w, "/**/%s(%s);", callback, bytes)
 	} else {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
+// NDJSON streams the items received from the channel to the client as
+// newline-delimited JSON objects, flushing after each of them. Streaming
+// stops once the channel is closed or the client has gone away.
+func NDJSON(w http.ResponseWriter, r *http.Request, items <-chan interface{}) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case item, ok := <-items:
+			if !ok {
+				return
+			}
+			if err := encoder.Encode(item); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// Error replies to the request with the error message & HTTP status code, in the
+// JSON envelope of AbortJSON for clients accepting JSON, or plain text otherwise.
+func Error(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if written(w) {
+		return
+	}
+	if strings.Contains(r.Header.Get("Accept"), "json") || strings.Contains(r.Header.Get("Content-Type"), "json") {
+		AbortJSON(w, r, status, M{"message": message})
+	} else {
+		http.Error(w, message, status)
+	}
+}
+
+// AbortJSON replies to the request with the HTTP status code and the error in the
+// consistent JSON envelope: {"error": v, "status": status, "request_id": id}, where
+// request_id is taken from the X-Request-Id header (of the response or request), if any.
+// NOTE the status can be sent only once, so nothing is written if the http.ResponseWriter
+// (e.g. wrapped by middleware) reports via Written() bool that the response has been written.
+func AbortJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	if written(w) {
+		return
+	}
+	var envelope = M{"error": v, "status": status}
+	if id := w.Header().Get("X-Request-Id"); id != "" {
+		envelope["request_id"] = id
+	} else if id := r.Header.Get("X-Request-Id"); id != "" {
+		envelope["request_id"] = id
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(envelope)
+}
+
+// written checks if the http.ResponseWriter records and reports the response as written.
+func written(w http.ResponseWriter) bool {
+	recorder, ok := w.(interface {
+		Written() bool
+	})
+	return ok && recorder.Written()
+}
+
+// Inline sends the given data (e.g. generated PDFs/images) with the
+// Content-Disposition of inline, so browsers render instead of downloading it.
+func Inline(w http.ResponseWriter, data []byte, filename string) {
+	var mimetype = mime.TypeByExtension(filepath.Ext(filename))
+	if mimetype == "" {
+		mimetype = http.DetectContentType(data)
+	}
+	w.Header().Set("Content-Type", mimetype)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": filepath.Base(filename)}))
+	w.Write(data)
+}
+
 // Renders a view (Pongo) and sends the rendered HTML string to the client.
 // Optional parameters: value, local variables for the view.
 // func Render(filename string, v ...interface{}) {}
 
-func init() {
-	var basedir = fs.Getcd(2)
+// Env returns the name of the active environment profile (e.g. development,
+// production, test) specified by the ENV or GO_ENV variable, if any.
+func Env() string {
+	return env.String("ENV", env.String("GO_ENV", ""))
+}
+
+// load reads the environment variables from .env under the given directory,
+// along with the ones from the active profile (e.g. .env.production).
+func load(basedir string) {
 	env.Set("basedir", basedir)
 	env.Load(path.Join(basedir, ".env"))
+	// values from the active profile (e.g. .env.production) override the base ones.
+	if profile := Env(); profile != "" {
+		env.Load(path.Join(basedir, ".env."+profile))
+	}
+}
+
+func init() {
+	load(fs.Getcd(2))
 }
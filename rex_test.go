@@ -0,0 +1,209 @@
+package rex
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSend(t *testing.T) {
+	Convey("rex.Send", t, func() {
+		response := httptest.NewRecorder()
+		Send(response, M{"name": "rex"})
+
+		var v M
+		So(response.Header().Get("Content-Type"), ShouldEqual, "application/json; charset=utf-8")
+		So(json.Unmarshal(response.Body.Bytes(), &v), ShouldBeNil)
+		So(v["name"], ShouldEqual, "rex")
+
+		response = httptest.NewRecorder()
+		Send(response, make(chan int))
+		So(response.Code, ShouldEqual, http.StatusInternalServerError)
+
+		defer func(origin bool) { debug = origin }(debug)
+		debug = false
+		response = httptest.NewRecorder()
+		Send(response, nil)
+		So(response.Body.String(), ShouldEqual, "null\n")
+
+		response = httptest.NewRecorder()
+		Send(response, M(nil))
+		So(response.Body.String(), ShouldEqual, "null\n")
+
+		response = httptest.NewRecorder()
+		Send(response, M{"name": "rex"})
+		So(response.Body.String(), ShouldEqual, `{"name":"rex"}`+"\n")
+
+		debug = true
+		response = httptest.NewRecorder()
+		Send(response, M{"name": "rex"})
+		So(response.Body.String(), ShouldEqual, "{\n  \"name\": \"rex\"\n}\n")
+
+		JSONIndent = "\t"
+		defer func() { JSONIndent = "" }()
+		response = httptest.NewRecorder()
+		Send(response, M{"name": "rex"})
+		So(response.Body.String(), ShouldEqual, "{\n\t\"name\": \"rex\"\n}\n")
+	})
+}
+
+func TestNDJSON(t *testing.T) {
+	Convey("rex.NDJSON", t, func() {
+		items := make(chan interface{})
+		go func() {
+			for index := 0; index < 3; index++ {
+				items <- M{"index": index}
+			}
+			close(items)
+		}()
+
+		request, _ := http.NewRequest("GET", "/", nil)
+		response := httptest.NewRecorder()
+		NDJSON(response, request, items)
+
+		So(response.Header().Get("Content-Type"), ShouldEqual, "application/x-ndjson")
+		So(response.Flushed, ShouldBeTrue)
+
+		lines := 0
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			var v M
+			So(json.Unmarshal(scanner.Bytes(), &v), ShouldBeNil)
+			So(v["index"], ShouldEqual, lines)
+			lines++
+		}
+		So(lines, ShouldEqual, 3)
+	})
+}
+
+func TestJSONP(t *testing.T) {
+	Convey("rex.JSONP", t, func() {
+		response := httptest.NewRecorder()
+		JSONP(response, "jQuery.callback_1", M{"name": "rex"})
+
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Header().Get("Content-Type"), ShouldEqual, "application/javascript; charset=utf-8")
+		So(response.Body.String(), ShouldEqual, `/**/jQuery.callback_1({"name":"rex"});`)
+
+		response = httptest.NewRecorder()
+		JSONP(response, "alert(1);//", M{"name": "rex"})
+		So(response.Code, ShouldEqual, http.StatusBadRequest)
+	})
+}
+
+func TestError(t *testing.T) {
+	Convey("rex.Error", t, func() {
+		request, _ := http.NewRequest("GET", "/", nil)
+		response := httptest.NewRecorder()
+		Error(response, request, http.StatusNotFound, "user not found")
+		So(response.Code, ShouldEqual, http.StatusNotFound)
+		So(response.Header().Get("Content-Type"), ShouldStartWith, "text/plain")
+		So(response.Body.String(), ShouldEqual, "user not found\n")
+
+		request.Header.Set("Accept", "application/json")
+		request.Header.Set("X-Request-Id", "42")
+		response = httptest.NewRecorder()
+		Error(response, request, http.StatusNotFound, "user not found")
+		So(response.Code, ShouldEqual, http.StatusNotFound)
+		So(response.Header().Get("Content-Type"), ShouldEqual, "application/json; charset=utf-8")
+		So(response.Body.String(), ShouldEqual, `{"error":{"message":"user not found"},"request_id":"42","status":404}`+"\n")
+	})
+}
+
+func TestAbortJSON(t *testing.T) {
+	Convey("rex.AbortJSON", t, func() {
+		request, _ := http.NewRequest("POST", "/", nil)
+		response := httptest.NewRecorder()
+		response.Header().Set("X-Request-Id", "7")
+		AbortJSON(response, request, http.StatusUnprocessableEntity, M{"fields": M{"email": "is invalid"}})
+		So(response.Code, ShouldEqual, http.StatusUnprocessableEntity)
+
+		var v M
+		json.Unmarshal(response.Body.Bytes(), &v)
+		So(v["status"], ShouldEqual, 422)
+		So(v["request_id"], ShouldEqual, "7")
+		So(v["error"], ShouldResemble, map[string]interface{}{"fields": map[string]interface{}{"email": "is invalid"}})
+	})
+}
+
+type writtenRecorder struct {
+	*httptest.ResponseRecorder
+	written bool
+}
+
+func (self *writtenRecorder) WriteHeader(status int) {
+	self.written = true
+	self.ResponseRecorder.WriteHeader(status)
+}
+
+func (self *writtenRecorder) Written() bool {
+	return self.written
+}
+
+func TestAbortJSONWritten(t *testing.T) {
+	Convey("rex.AbortJSON (Written)", t, func() {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Accept", "application/json")
+		response := &writtenRecorder{ResponseRecorder: httptest.NewRecorder()}
+		response.WriteHeader(http.StatusAccepted)
+
+		Error(response, request, http.StatusInternalServerError, "failed")
+		AbortJSON(response, request, http.StatusInternalServerError, "failed")
+		So(response.Code, ShouldEqual, http.StatusAccepted)
+		So(response.Header().Get("Content-Type"), ShouldBeEmpty)
+		So(response.Body.Len(), ShouldEqual, 0)
+	})
+}
+
+func TestEnv(t *testing.T) {
+	Convey("rex.Env", t, func() {
+		os.Unsetenv("ENV")
+		os.Setenv("GO_ENV", "test")
+		defer os.Unsetenv("GO_ENV")
+		So(Env(), ShouldEqual, "test")
+
+		os.Setenv("ENV", "production")
+		defer os.Unsetenv("ENV")
+		So(Env(), ShouldEqual, "production")
+	})
+}
+
+func TestLoad(t *testing.T) {
+	Convey("rex.load", t, func() {
+		tempdir, _ := ioutil.TempDir("", "rex")
+		defer os.RemoveAll(tempdir)
+		ioutil.WriteFile(filepath.Join(tempdir, ".env"), []byte("REX_DSN=base\nREX_SECRET=base\n"), 0644)
+		ioutil.WriteFile(filepath.Join(tempdir, ".env.staging"), []byte("REX_DSN=staging\n"), 0644)
+		defer os.Unsetenv("REX_DSN")
+		defer os.Unsetenv("REX_SECRET")
+		defer os.Setenv("basedir", os.Getenv("basedir"))
+
+		os.Setenv("ENV", "staging")
+		defer os.Unsetenv("ENV")
+		load(tempdir)
+		So(os.Getenv("REX_DSN"), ShouldEqual, "staging")
+		So(os.Getenv("REX_SECRET"), ShouldEqual, "base")
+	})
+}
+
+func TestInline(t *testing.T) {
+	Convey("rex.Inline", t, func() {
+		response := httptest.NewRecorder()
+		Inline(response, []byte("%PDF-1.4"), "report.pdf")
+		So(response.Header().Get("Content-Type"), ShouldEqual, "application/pdf")
+		So(response.Header().Get("Content-Disposition"), ShouldEqual, "inline; filename=report.pdf")
+		So(response.Body.String(), ShouldEqual, "%PDF-1.4")
+
+		response = httptest.NewRecorder()
+		Inline(response, []byte("\x89PNG\r\n\x1a\n"), "avatar")
+		So(response.Header().Get("Content-Type"), ShouldEqual, "image/png")
+		So(response.Header().Get("Content-Disposition"), ShouldEqual, "inline; filename=avatar")
+	})
+}
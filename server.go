@@ -1,13 +1,24 @@
 package rex
 
 import (
+	"context"
+	"expvar"
 	"flag"
 	"fmt"
+	"html/template"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -19,22 +30,39 @@ var (
 	debug    bool
 	port     int
 	maxprocs int
+	profiler bool
+	socket   string
 
 	once sync.Once
 )
 
+// timeout to wait for the in-flight requests on shutting down the server.
+var shutdownTimeout = 10 * time.Second
+
 type server struct {
 	middleware *middleware
 	mux        *mux.Router
-	ready      bool
+	ready      sync.Once
 	subservers []*server
+	hooks      []func()
+	// regular expressions of the named route variables, shared with subservers.
+	constraints map[string]string
+	// directories served by FileServer under their prefixes & the content hashes of the assets.
+	assets       map[string]string
+	fingerprints map[string]string
+	mutex        sync.Mutex
 }
 
+// variable matches the route variables without regular expression, e.g. {id}.
+var variable = regexp.MustCompile(`\{([^{}:]+)\}`)
+
 func New() *server {
 	self := &server{
-		middleware: new(middleware),
-		mux:        mux.NewRouter().StrictSlash(true),
+		middleware:  new(middleware),
+		mux:         mux.NewRouter().StrictSlash(true),
+		constraints: make(map[string]string),
 	}
+	self.mux.MethodNotAllowedHandler = http.HandlerFunc(self.methodNotAllowed)
 	self.configure()
 	return self
 }
@@ -44,13 +72,40 @@ func (self *server) configure() {
 		flag.BoolVar(&debug, "debug", env.Bool("DEBUG", true), "flag to toggle debug mode")
 		flag.IntVar(&port, "port", env.Int("PORT", 5000), "port to run the application server")
 		flag.IntVar(&maxprocs, "maxprocs", env.Int("MAXPROCS", runtime.NumCPU()), "maximum cpu processes to run the server")
+		flag.StringVar(&socket, "socket", env.String("SOCKET", ""), "unix domain socket to run the application server instead of port")
+		flag.BoolVar(&profiler, "profiler", env.Bool("PROFILER", false), "flag to expose the pprof & expvar endpoints")
 		flag.Parse()
+		logging()
 	})
 }
 
-// build constructs all server/subservers along with their middleware modules chain.
+// logging configures the level & format of the logs via LOG_LEVEL (defaults to debug
+// in debug mode, info otherwise) & LOG_FORMAT (text or json for structured logs).
+func logging() {
+	var level = log.InfoLevel
+	if debug {
+		level = log.DebugLevel
+	}
+	if value := env.String("LOG_LEVEL", ""); value != "" {
+		if parsed, err := log.ParseLevel(strings.ToLower(value)); err == nil {
+			level = parsed
+		} else {
+			logger.Warnf("Invalid LOG_LEVEL: %v", err)
+		}
+	}
+	log.SetLevel(level)
+
+	if strings.ToLower(env.String("LOG_FORMAT", "text")) == "json" {
+		log.SetFormatter(new(log.JSONFormatter))
+	} else {
+		log.SetFormatter(new(log.TextFormatter))
+	}
+}
+
+// build constructs all server/subservers along with their middleware modules chain,
+// only once, so serving the requests afterwards costs a single ServeHTTP call.
 func (self *server) build() http.Handler {
-	if !self.ready {
+	self.ready.Do(func() {
 		// * add server mux into middlware stack to serve as final http.Handler.
 		self.Use(func(http.Handler) http.Handler {
 			return self.mux
@@ -62,8 +117,10 @@ func (self *server) build() http.Handler {
 				return server.mux
 			})
 		}
-		self.ready = true
-	}
+		for _, warning := range self.Check() {
+			logger.Warnf("Middleware %s", warning)
+		}
+	})
 	return self.middleware
 }
 
@@ -72,7 +129,11 @@ func (self *server) register(pattern string, handler interface{}, methods ...str
 	var name = strings.Join(methods, "|") + ":" + pattern
 	// finds the full function name (with package) as its mappings.
 	//var name = runtime.FuncForPC(reflect.ValueOf(handler).Pointer()).Name()
+	self.handle(name, self.constrain(pattern, self.constraints), handler, methods...)
+}
 
+// handle adds the named route of the (constrained) pattern into Gorilla mux.
+func (self *server) handle(name, pattern string, handler interface{}, methods ...string) {
 	switch H := handler.(type) {
 	case http.Handler:
 		self.mux.Handle(pattern, H).Methods(methods...).Name(name)
@@ -85,19 +146,57 @@ func (self *server) register(pattern string, handler interface{}, methods ...str
 	}
 }
 
+// constrain injects the regular expressions of the given constraints into
+// the matching route variables without one, e.g. {id} becomes {id:[0-9]+}.
+func (self *server) constrain(pattern string, constraints map[string]string) string {
+	return variable.ReplaceAllStringFunc(pattern, func(match string) string {
+		name := match[1 : len(match)-1]
+		if expr, exists := constraints[name]; exists {
+			return "{" + name + ":" + expr + "}"
+		}
+		return match
+	})
+}
+
+// Constrain restricts the route variable of the given name to the regular expression
+// in the routes registered afterwards (including subservers), unless the route gives
+// its own, e.g. Constrain("id", "[0-9]+") makes "/users/{id}" match numeric ids only.
+func (self *server) Constrain(name, pattern string) {
+	self.constraints[name] = pattern
+}
+
+// GetInt is a shortcut for Get with all route variables restricted to numbers,
+// e.g. GetInt("/users/{id}", handler) never receives a non-numeric id.
+func (self *server) GetInt(pattern string, handler interface{}) {
+	var constraints = make(map[string]string)
+	for _, match := range variable.FindAllStringSubmatch(pattern, -1) {
+		constraints[match[1]] = "[0-9]+"
+	}
+	self.handle("GET:"+pattern, self.constrain(pattern, constraints), handler, "GET")
+}
+
 // Any maps most common HTTP methods request to the given `http.Handler`.
 // Supports: GET | POST | PUT | DELETE | OPTIONS | HEAD
 func (self *server) Any(pattern string, handler interface{}) {
 	self.register(pattern, handler, "GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD")
 }
 
+// Alias maps the HTTP requests of all the given patterns (e.g. "/" and "/home") to the same handler,
+// each pattern is registered as a separate route with its own name.
+func (self *server) Alias(patterns []string, handler interface{}, methods ...string) {
+	for _, pattern := range patterns {
+		self.register(pattern, handler, methods...)
+	}
+}
+
 // Group creates a new application group under the given path prefix.
 func (self *server) Group(prefix string) *server {
 	var middleware = new(middleware)
 	self.mux.PathPrefix(prefix).Handler(middleware)
 	var mux = self.mux.PathPrefix(prefix).Subrouter()
 
-	server := &server{middleware: middleware, mux: mux}
+	server := &server{middleware: middleware, mux: mux, constraints: self.constraints}
+	mux.MethodNotAllowedHandler = http.HandlerFunc(server.methodNotAllowed)
 	self.subservers = append(self.subservers, server)
 	return server
 }
@@ -108,11 +207,61 @@ func (self *server) Host(domain string) *server {
   self.mux.Host(domain).Handler(middleware)
   var mux = self.mux.Host(domain).Subrouter()
 
-	server := &server{middleware: middleware, mux: mux}
+	server := &server{middleware: middleware, mux: mux, constraints: self.constraints}
+	mux.MethodNotAllowedHandler = http.HandlerFunc(server.methodNotAllowed)
 	self.subservers = append(self.subservers, server)
 	return server
 }
 
+// StrictSlash defines the trailing slash behavior of the routes registered afterwards
+// (enabled by default): when true, "/path/" redirects to "/path" if only the latter is
+// registered & vice versa, when false, they are different paths & never redirected.
+// Use middleware.TrailingSlash to canonicalize all paths regardless of the routes.
+func (self *server) StrictSlash(value bool) {
+	self.mux.StrictSlash(value)
+}
+
+// SkipClean defines the path cleaning behavior of the server (disabled by default):
+// when true, paths like "/path//to" are matched as they are, instead of being
+// redirected to the cleaned "/path/to" first.
+func (self *server) SkipClean(value bool) {
+	self.mux.SkipClean(value)
+}
+
+// allowed returns the sorted HTTP methods the path of the request is registered for.
+func (self *server) allowed(r *http.Request) (methods []string) {
+	var seen = make(map[string]bool)
+	self.mux.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		candidates, _ := route.GetMethods()
+		for _, method := range candidates {
+			request := *r
+			request.Method = method
+			if !seen[method] && route.Match(&request, new(mux.RouteMatch)) {
+				seen[method] = true
+				methods = append(methods, method)
+			}
+		}
+		return nil
+	})
+	if len(methods) > 0 && !seen["OPTIONS"] {
+		methods = append(methods, "OPTIONS")
+	}
+	sort.Strings(methods)
+	return
+}
+
+// methodNotAllowed replies the requests whose path is registered for other methods
+// with the Allow header, OPTIONS requests are answered automatically (e.g. for
+// CORS preflight) unless registered explicitly, others get 405 Method Not Allowed.
+func (self *server) methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", strings.Join(self.allowed(r), ", "))
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
 // Name returns route name for the given request, if any.
 func (self *server) Name(r *http.Request) (name string) {
 	var match mux.RouteMatch
@@ -122,18 +271,151 @@ func (self *server) Name(r *http.Request) (name string) {
 	return name
 }
 
+// URL builds the URL of the named route using the given variables in
+// key/value pairs, e.g. URL("GET:/users/{id}", "id", "42") gives "/users/42".
+func (self *server) URL(name string, pairs ...string) (string, error) {
+	route := self.mux.Get(name)
+	if route == nil {
+		return "", fmt.Errorf("route %q not found", name)
+	}
+	url, err := route.URL(pairs...)
+	if err != nil {
+		return "", fmt.Errorf("failed to build URL of route %q: %v", name, err)
+	}
+	return url.String(), nil
+}
+
+// FuncMap returns the template functions bound to the server for html/template, e.g.
+// template.New("page").Funcs(app.FuncMap()), which supports {{ url "GET:/users/{id}" "id" "42" }}.
+func (self *server) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"url":   self.URL,
+		"asset": self.Asset,
+	}
+}
+
+// Asset returns the URL of the static file served by FileServer along with its content hash,
+// e.g. Asset("/assets/css/app.css") gives "/assets/css/app.css?v=5d41402a", which resolves to the
+// same file, so it can be cached for long (see FileServerOptions.MaxAge) and busted on changes.
+// Hashes are computed once, except in debug mode. Unknown files are returned as they are.
+func (self *server) Asset(url string) string {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+
+	if hash, exists := self.fingerprints[url]; exists && !debug {
+		return url + "?v=" + hash
+	}
+	for prefix, dir := range self.assets {
+		if !strings.HasPrefix(url, prefix) {
+			continue
+		}
+		filename := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+strings.TrimPrefix(url, prefix))))
+		if hash := fingerprint(filename); hash != "" {
+			if self.fingerprints == nil {
+				self.fingerprints = make(map[string]string)
+			}
+			self.fingerprints[url] = hash
+			return url + "?v=" + hash
+		}
+	}
+	return url
+}
+
+// FileServerOptions configures the file server.
+type FileServerOptions struct {
+	// Listing enables listing the contents of directories without index.html,
+	// it is disabled by default to avoid exposing the files unintentionally.
+	Listing bool
+	// MaxAge sets the Cache-Control max-age of the served files, if any.
+	MaxAge time.Duration
+	// ETag emits the ETag of the served files based on their size & modification
+	// time, so browsers can conditionally request them with If-None-Match.
+	// Last-Modified is always emitted for If-Modified-Since requests.
+	ETag bool
+}
+
 // FileServer registers a handler to serve HTTP (GET|HEAD) requests
 // with the contents of file system under the given directory.
+// Directory listings are disabled, use FileServerWithOptions to enable it.
 func (self *server) FileServer(prefix, dir string) {
+	self.FileServerWithOptions(prefix, dir, FileServerOptions{})
+}
+
+// FileServerWithOptions registers a handler to serve HTTP (GET|HEAD) requests
+// with the contents of file system under the given directory using the options.
+func (self *server) FileServerWithOptions(prefix, dir string, options FileServerOptions) {
 	if abs, err := filepath.Abs(dir); err == nil {
-		fs := http.StripPrefix(prefix, http.FileServer(http.Dir(abs)))
+		if self.assets == nil {
+			self.assets = make(map[string]string)
+		}
+		self.assets[prefix] = abs
+		var root = http.Dir(abs)
+		var files = http.FileServer(root)
+		fs := http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !options.Listing && isDir(root, path.Clean("/"+r.URL.Path)) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			if options.MaxAge > 0 {
+				w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(options.MaxAge.Seconds())))
+			}
+			if options.ETag {
+				if tag := etag(root, path.Clean("/"+r.URL.Path)); tag != "" {
+					w.Header().Set("ETag", tag)
+				}
+			}
+			files.ServeHTTP(w, r)
+		}))
 		self.mux.PathPrefix(prefix).Handler(fs)
 	} else {
 		panic("Failed to setup file server: " + err.Error())
 	}
 }
 
-// Use add the middleware module into the stack chain.
+// Mount serves the requests under the given path prefix with the handler (e.g. a
+// third-party admin UI or another application), which sees the paths with the
+// prefix stripped, i.e. "/admin/users" becomes "/users" when mounted at "/admin".
+// Mounted handlers run behind the middleware modules of the server.
+func (self *server) Mount(prefix string, handler http.Handler) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		self.mux.PathPrefix("/").Handler(handler)
+		return
+	}
+	mounted := http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "" {
+			r.URL.Path = "/"
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	// matches the prefix on path segment boundary only, i.e. "/admin" & "/admin/users",
+	// but never the siblings like "/administrator".
+	self.mux.PathPrefix(prefix + "/").Handler(mounted)
+	self.mux.Path(prefix).Handler(mounted)
+}
+
+// Profiler registers the net/http/pprof handlers under "<prefix>/pprof/" and the expvar
+// handler at "<prefix>/vars", only if enabled via the -profiler flag or PROFILER env.
+// The endpoints run behind the middleware modules, so they can be protected, e.g. BasicAuth.
+func (self *server) Profiler(prefix string) {
+	if !profiler {
+		return
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	self.mux.HandleFunc(prefix+"/pprof/cmdline", pprof.Cmdline)
+	self.mux.HandleFunc(prefix+"/pprof/profile", pprof.Profile)
+	self.mux.HandleFunc(prefix+"/pprof/symbol", pprof.Symbol)
+	self.mux.HandleFunc(prefix+"/pprof/trace", pprof.Trace)
+	self.mux.HandleFunc(prefix+"/pprof/{name}", func(w http.ResponseWriter, r *http.Request) {
+		pprof.Handler(mux.Vars(r)["name"]).ServeHTTP(w, r)
+	})
+	self.mux.HandleFunc(prefix+"/pprof/", pprof.Index)
+	self.mux.Handle(prefix+"/vars", expvar.Handler())
+}
+
+// Use add the middleware module into the stack chain, modules run in the order
+// they were added: Use(A); Use(B) makes A the outermost one, which sees the
+// request first (before B) and the response last (after B).
 func (self *server) Use(modules ...func(http.Handler) http.Handler) {
 	self.middleware.stack = append(self.middleware.stack, modules...)
 }
@@ -193,21 +475,106 @@ func (self *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	self.build().ServeHTTP(w, r)
 }
 
-// Run starts the application server to serve incoming requests at the given address.
-func (self *server) Run() {
+// OnStop registers the hook to run after the server has been shut down
+// gracefully, hooks run in the order they were registered.
+func (self *server) OnStop(hook func()) {
+	self.hooks = append(self.hooks, hook)
+}
+
+// shutdown stops accepting new requests, waits for the in-flight ones
+// to complete (within the timeout), then runs the OnStop hooks.
+func (self *server) shutdown(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Errorf("Failed to shutdown the server gracefully: %v", err)
+	}
+	for _, hook := range self.hooks {
+		hook()
+	}
+}
+
+// Run starts the application server to serve incoming requests at the given port
+// (or Unix domain socket if given via the -socket flag or SOCKET env),
+// the server will be shut down gracefully on receiving SIGINT/SIGTERM. It returns
+// nil once shut down, or the error if the server failed to start.
+func (self *server) Run() error {
+	if socket != "" {
+		return self.RunUnix(socket)
+	}
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		if inUse(err) {
+			return fmt.Errorf("Port %d is already in use by another process, stop it or change the PORT: %v", port, err)
+		}
+		return err
+	}
+	return self.Serve(listener)
+}
+
+// RunUnix starts the application server on the given Unix domain socket (e.g. for
+// nginx on the same host) like Run, the stale socket file left by the previous run
+// is removed on startup (unless another process still listens on it), the socket
+// is group writable and removed on shutdown.
+func (self *server) RunUnix(filename string) error {
+	if stat, err := os.Stat(filename); err == nil && stat.Mode()&os.ModeSocket != 0 {
+		conn, err := net.Dial("unix", filename)
+		if err == nil {
+			conn.Close()
+			return fmt.Errorf("Socket %s is already in use by another process, stop it or change the SOCKET: %v",
+				filename, syscall.EADDRINUSE)
+		}
+		if refused(err) {
+			os.Remove(filename)
+		}
+	}
+	listener, err := net.Listen("unix", filename)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(filename)
+	if err = os.Chmod(filename, 0660); err != nil {
+		listener.Close()
+		return err
+	}
+	return self.Serve(listener)
+}
+
+// Serve accepts incoming requests on the given listener (e.g. ":0" for tests or the
+// one passed by systemd socket activation), the server will be shut down gracefully
+// on receiving SIGINT/SIGTERM. It returns nil once shut down, or the error if failed.
+func (self *server) Serve(listener net.Listener) error {
 	runtime.GOMAXPROCS(maxprocs)
 
+	var server = &http.Server{Handler: self}
+	var stopped = make(chan bool)
+	var signals = make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 	go func() {
-		time.Sleep(500 * time.Millisecond)
-		log.Infof("Application server is listening at %d", port)
+		if _, ok := <-signals; ok {
+			self.shutdown(server)
+		}
+		close(stopped)
 	}()
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), self); err != nil {
-		log.Fatalf("Failed to start the server: %v", err)
+	logger.Infof("Application server is listening at %s", listener.Addr())
+	if err := server.Serve(listener); err != http.ErrServerClosed {
+		signal.Stop(signals)
+		close(signals)
+		return err
+	}
+	<-stopped
+	return nil
+}
+
+// MustRun starts the application server like Run, but exits the process if it failed to start.
+func (self *server) MustRun() {
+	if err := self.Run(); err != nil {
+		logger.Fatalf("Failed to start the server: %v", err)
 	}
 }
 
 // Vars returns the route variables for the current request, if any.
 func (self *server) Vars(r *http.Request) map[string]string {
-	return mux.Vars(r)
+	return Vars(r)
 }
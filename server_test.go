@@ -1,13 +1,20 @@
 package rex
 
 import (
+	"bytes"
+	"html/template"
 	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
+	"time"
 
+	log "github.com/Sirupsen/logrus"
 	"github.com/goanywhere/env"
 	mw "github.com/goanywhere/rex/middleware"
 	. "github.com/smartystreets/goconvey/convey"
@@ -120,6 +127,27 @@ func TestAny(t *testing.T) {
 	})
 }
 
+func TestAlias(t *testing.T) {
+	Convey("rex.Alias", t, func() {
+		app := New()
+		app.Alias([]string{"/", "/home"}, func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, app.Name(r))
+		}, "GET")
+
+		request, _ := http.NewRequest("GET", "/", nil)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Body.String(), ShouldEqual, "GET:/")
+
+		request, _ = http.NewRequest("GET", "/home", nil)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Body.String(), ShouldEqual, "GET:/home")
+	})
+}
+
 func TestName(t *testing.T) {
 	Convey("rex.Name", t, func() {
 		app := New()
@@ -134,6 +162,31 @@ func TestName(t *testing.T) {
 	})
 }
 
+func TestURL(t *testing.T) {
+	Convey("rex.URL", t, func() {
+		app := New()
+		app.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+
+		url, err := app.URL("GET:/users/{id}", "id", "42")
+		So(err, ShouldBeNil)
+		So(url, ShouldEqual, "/users/42")
+
+		_, err = app.URL("GET:/users/{id}")
+		So(err, ShouldNotBeNil)
+
+		_, err = app.URL("GET:/missing")
+		So(err.Error(), ShouldEqual, `route "GET:/missing" not found`)
+
+		var buffer = new(bytes.Buffer)
+		page := template.Must(template.New("page").Funcs(app.FuncMap()).Parse(`<a href="{{ url "GET:/users/{id}" "id" .ID }}">`))
+		So(page.Execute(buffer, map[string]string{"ID": "42"}), ShouldBeNil)
+		So(buffer.String(), ShouldEqual, `<a href="/users/42">`)
+
+		page = template.Must(template.New("page").Funcs(app.FuncMap()).Parse(`{{ url "GET:/missing" }}`))
+		So(page.Execute(new(bytes.Buffer), nil), ShouldNotBeNil)
+	})
+}
+
 func TestGet(t *testing.T) {
 	app := New()
 	app.Get("/", func(w http.ResponseWriter, r *http.Request) {
@@ -330,11 +383,48 @@ func TestFileServer(t *testing.T) {
 		app.ServeHTTP(response, request)
 		So(response.Code, ShouldEqual, http.StatusOK)
 
-		filename = "index.html"
 		request, _ = http.NewRequest("HEAD", prefix, nil)
 		response = httptest.NewRecorder()
 		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusForbidden)
+
+		app = New()
+		app.FileServerWithOptions(prefix, tempdir, FileServerOptions{Listing: true})
+
+		request, _ = http.NewRequest("GET", prefix, nil)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Body.String(), ShouldContainSubstring, filename)
+	})
+}
+
+func TestFileServerWithOptions(t *testing.T) {
+	Convey("rex.FileServerWithOptions", t, func() {
+		var prefix = "/assets/"
+		tempdir := os.TempDir()
+		filename := path.Join(tempdir, "app.css")
+		ioutil.WriteFile(filename, []byte("body {}"), 0644)
+		defer os.Remove(filename)
+
+		app := New()
+		app.FileServerWithOptions(prefix, tempdir, FileServerOptions{MaxAge: time.Hour, ETag: true})
+
+		request, _ := http.NewRequest("GET", path.Join(prefix, "app.css"), nil)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
 		So(response.Code, ShouldEqual, http.StatusOK)
+		So(response.Header().Get("Cache-Control"), ShouldEqual, "public, max-age=3600")
+		So(response.Header().Get("Last-Modified"), ShouldNotBeEmpty)
+
+		tag := response.Header().Get("ETag")
+		So(tag, ShouldStartWith, `W/"`)
+
+		request, _ = http.NewRequest("GET", path.Join(prefix, "app.css"), nil)
+		request.Header.Set("If-None-Match", tag)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusNotModified)
 	})
 }
 
@@ -370,3 +460,348 @@ func TestVars(t *testing.T) {
 		app.ServeHTTP(response, request)
 	})
 }
+
+func TestOnStop(t *testing.T) {
+	Convey("rex.OnStop", t, func() {
+		var order []string
+		var started = make(chan bool)
+		app := New()
+		app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			order = append(order, "request")
+		})
+		app.OnStop(func() { order = append(order, "first") })
+		app.OnStop(func() { order = append(order, "second") })
+
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		server := &http.Server{Handler: app}
+		go server.Serve(listener)
+
+		var code int
+		var done = make(chan bool)
+		go func() {
+			if response, err := http.Get("http://" + listener.Addr().String()); err == nil {
+				code = response.StatusCode
+				response.Body.Close()
+			}
+			close(done)
+		}()
+		<-started
+		app.shutdown(server)
+		<-done
+
+		So(code, ShouldEqual, http.StatusOK)
+		So(order, ShouldResemble, []string{"request", "first", "second"})
+	})
+}
+
+func TestRun(t *testing.T) {
+	Convey("rex.Run", t, func() {
+		listener, _ := net.Listen("tcp", ":0")
+		defer listener.Close()
+
+		defer func(origin int) { port = origin }(port)
+		port = listener.Addr().(*net.TCPAddr).Port
+		err := New().Run()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "already in use")
+	})
+}
+
+func TestServe(t *testing.T) {
+	Convey("rex.Serve", t, func() {
+		app := New()
+		app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "rex")
+		})
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		var served = make(chan error)
+		go func() { served <- app.Serve(listener) }()
+
+		response, err := http.Get("http://" + listener.Addr().String())
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		So(string(body), ShouldEqual, "rex")
+
+		listener.Close()
+		So(<-served, ShouldNotBeNil)
+	})
+}
+
+func TestRunUnix(t *testing.T) {
+	Convey("rex.RunUnix", t, func() {
+		app := New()
+		app.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "rex")
+		})
+		tempdir, _ := ioutil.TempDir("", "rex")
+		defer os.RemoveAll(tempdir)
+		filename := filepath.Join(tempdir, "rex.sock")
+		// stale socket file left by the previous run.
+		stale, _ := net.Listen("unix", filename)
+		stale.(*net.UnixListener).SetUnlinkOnClose(false)
+		stale.Close()
+
+		go app.RunUnix(filename)
+
+		var response *http.Response
+		var err error
+		client := &http.Client{Transport: &http.Transport{
+			Dial: func(network, address string) (net.Conn, error) {
+				return net.Dial("unix", filename)
+			},
+		}}
+		for attempt := 0; attempt < 50; attempt++ {
+			if response, err = client.Get("http://unix/"); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		So(err, ShouldBeNil)
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		So(string(body), ShouldEqual, "rex")
+
+		stat, _ := os.Stat(filename)
+		So(stat.Mode().Perm(), ShouldEqual, os.FileMode(0660))
+
+		// the socket is being listened on by the running server.
+		err = New().RunUnix(filename)
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "already in use")
+		response, err = client.Get("http://unix/")
+		So(err, ShouldBeNil)
+		response.Body.Close()
+	})
+}
+
+func TestConstrain(t *testing.T) {
+	Convey("rex.Constrain", t, func() {
+		app := New()
+		app.Constrain("id", "[0-9]+")
+		app.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "user:"+app.Vars(r)["id"])
+		})
+		app.Get("/posts/{id:[a-z]+}", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "post:"+app.Vars(r)["id"])
+		})
+		group := app.Group("/v1")
+		group.Get("/accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "v1:"+app.Vars(r)["id"])
+		})
+		app.GetInt("/items/{item}", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "item:"+app.Vars(r)["item"])
+		})
+
+		for path, expected := range map[string]int{
+			"/users/42": http.StatusOK, "/users/me": http.StatusNotFound,
+			"/posts/hi": http.StatusOK, "/posts/42": http.StatusNotFound,
+			"/v1/accounts/7": http.StatusOK, "/v1/accounts/x": http.StatusNotFound,
+			"/items/3": http.StatusOK, "/items/x": http.StatusNotFound,
+		} {
+			request, _ := http.NewRequest("GET", path, nil)
+			response := httptest.NewRecorder()
+			app.ServeHTTP(response, request)
+			So(response.Code, ShouldEqual, expected)
+		}
+
+		url, err := app.URL("GET:/users/{id}", "id", "42")
+		So(err, ShouldBeNil)
+		So(url, ShouldEqual, "/users/42")
+		_, err = app.URL("GET:/items/{item}", "item", "x")
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	Convey("rex.MethodNotAllowed", t, func() {
+		app := New()
+		app.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+		app.Post("/users", func(w http.ResponseWriter, r *http.Request) {})
+		app.Delete("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+		group := app.Group("/v1")
+		group.Put("/accounts", func(w http.ResponseWriter, r *http.Request) {})
+
+		request, _ := http.NewRequest("PUT", "/users", nil)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		So(response.Header().Get("Allow"), ShouldEqual, "GET, OPTIONS, POST")
+
+		request, _ = http.NewRequest("OPTIONS", "/users/42", nil)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusNoContent)
+		So(response.Header().Get("Allow"), ShouldEqual, "DELETE, OPTIONS")
+
+		request, _ = http.NewRequest("GET", "/v1/accounts", nil)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusMethodNotAllowed)
+		So(response.Header().Get("Allow"), ShouldEqual, "OPTIONS, PUT")
+
+		request, _ = http.NewRequest("GET", "/missing", nil)
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusNotFound)
+	})
+}
+
+func TestMount(t *testing.T) {
+	Convey("rex.Mount", t, func() {
+		admin := http.NewServeMux()
+		admin.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "admin:"+r.URL.Path)
+		})
+
+		app := New()
+		app.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Powered-By", "rex")
+				next.ServeHTTP(w, r)
+			})
+		})
+		app.Mount("/admin/", admin)
+
+		for path, expected := range map[string]string{
+			"/admin/users": "admin:/users",
+			"/admin":       "admin:/",
+		} {
+			request, _ := http.NewRequest("GET", path, nil)
+			response := httptest.NewRecorder()
+			app.ServeHTTP(response, request)
+			So(response.Body.String(), ShouldEqual, expected)
+			So(response.Header().Get("X-Powered-By"), ShouldEqual, "rex")
+		}
+		for _, path := range []string{"/administrator", "/adminx/y"} {
+			request, _ := http.NewRequest("GET", path, nil)
+			response := httptest.NewRecorder()
+			app.ServeHTTP(response, request)
+			So(response.Code, ShouldEqual, http.StatusNotFound)
+		}
+	})
+}
+
+func TestProfiler(t *testing.T) {
+	Convey("rex.Profiler", t, func() {
+		app := New()
+		app.Profiler("/debug")
+		request, _ := http.NewRequest("GET", "/debug/vars", nil)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusNotFound)
+
+		profiler = true
+		defer func() { profiler = false }()
+		app = New()
+		app.Profiler("/debug/")
+		for path, expected := range map[string]string{
+			"/debug/vars":                    "memstats",
+			"/debug/pprof/":                  "goroutine",
+			"/debug/pprof/goroutine?debug=1": "goroutine profile",
+			"/debug/pprof/cmdline":           "",
+		} {
+			request, _ := http.NewRequest("GET", path, nil)
+			response := httptest.NewRecorder()
+			app.ServeHTTP(response, request)
+			So(response.Code, ShouldEqual, http.StatusOK)
+			So(response.Body.String(), ShouldContainSubstring, expected)
+		}
+	})
+}
+
+func TestStrictSlash(t *testing.T) {
+	Convey("rex.StrictSlash", t, func() {
+		app := New()
+		app.StrictSlash(false)
+		app.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+
+		request, _ := http.NewRequest("GET", "/users/", nil)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusNotFound)
+
+		app = New()
+		app.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+		response = httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Code, ShouldEqual, http.StatusMovedPermanently)
+	})
+}
+
+func TestSkipClean(t *testing.T) {
+	Convey("rex.SkipClean", t, func() {
+		app := New()
+		app.SkipClean(true)
+		app.Get("/users//{id}", func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, app.Vars(r)["id"])
+		})
+
+		request, _ := http.NewRequest("GET", "/users//42", nil)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Body.String(), ShouldEqual, "42")
+	})
+}
+
+func TestLogging(t *testing.T) {
+	Convey("rex.logging", t, func() {
+		defer func(origin bool) { debug = origin }(debug)
+		defer logging()
+
+		debug = false
+		logging()
+		So(log.GetLevel(), ShouldEqual, log.InfoLevel)
+		So(log.StandardLogger().Formatter, ShouldHaveSameTypeAs, new(log.TextFormatter))
+
+		debug = true
+		logging()
+		So(log.GetLevel(), ShouldEqual, log.DebugLevel)
+
+		env.Set("LOG_LEVEL", "WARN")
+		env.Set("LOG_FORMAT", "json")
+		defer os.Unsetenv("LOG_LEVEL")
+		defer os.Unsetenv("LOG_FORMAT")
+		logging()
+		So(log.GetLevel(), ShouldEqual, log.WarnLevel)
+		So(log.StandardLogger().Formatter, ShouldHaveSameTypeAs, new(log.JSONFormatter))
+	})
+}
+
+func TestAsset(t *testing.T) {
+	Convey("rex.Asset", t, func() {
+		defer func(origin bool) { debug = origin }(debug)
+		debug = false
+
+		tempdir, _ := ioutil.TempDir("", "rex")
+		defer os.RemoveAll(tempdir)
+		os.MkdirAll(filepath.Join(tempdir, "css"), 0755)
+		filename := filepath.Join(tempdir, "css", "app.css")
+		ioutil.WriteFile(filename, []byte("hello"), 0644)
+
+		app := New()
+		app.FileServer("/assets/", tempdir)
+		url := app.Asset("/assets/css/app.css")
+		So(url, ShouldEqual, "/assets/css/app.css?v=5d41402a")
+		So(app.Asset("/assets/css/missing.css"), ShouldEqual, "/assets/css/missing.css")
+		So(app.Asset("/other/app.css"), ShouldEqual, "/other/app.css")
+
+		request, _ := http.NewRequest("GET", url, nil)
+		response := httptest.NewRecorder()
+		app.ServeHTTP(response, request)
+		So(response.Body.String(), ShouldEqual, "hello")
+
+		// hashes are computed once, except in debug mode.
+		ioutil.WriteFile(filename, []byte("world"), 0644)
+		So(app.Asset("/assets/css/app.css"), ShouldEqual, url)
+		debug = true
+		So(app.Asset("/assets/css/app.css"), ShouldEqual, "/assets/css/app.css?v=7d793037")
+
+		var buffer bytes.Buffer
+		page := template.Must(template.New("page").Funcs(app.FuncMap()).Parse(`{{ asset "/assets/css/app.css" }}`))
+		page.Execute(&buffer, nil)
+		So(buffer.String(), ShouldEqual, "/assets/css/app.css?v=7d793037")
+	})
+}
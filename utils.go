@@ -1 +1,84 @@
 package rex
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"syscall"
+)
+
+// isDir checks if the given name is a directory without index.html under the file system.
+func isDir(fs http.FileSystem, name string) bool {
+	file, err := fs.Open(name)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	if stat, err := file.Stat(); err != nil || !stat.IsDir() {
+		return false
+	}
+
+	if index, err := fs.Open(path.Join(name, "index.html")); err == nil {
+		index.Close()
+		return false
+	}
+	return true
+}
+
+// etag generates the weak ETag of the given regular file under the file system
+// based on its size & modification time.
+func etag(fs http.FileSystem, name string) string {
+	file, err := fs.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return ""
+	}
+	return fmt.Sprintf(`W/"%x-%x"`, stat.Size(), stat.ModTime().UnixNano())
+}
+
+// inUse checks if the error is caused by binding to an address already in use.
+func inUse(err error) bool {
+	return errno(err) == syscall.EADDRINUSE
+}
+
+// refused checks if the error is caused by dialing an address nobody listens on.
+func refused(err error) bool {
+	return errno(err) == syscall.ECONNREFUSED
+}
+
+// errno unwraps the underlying system call error of the network operation.
+func errno(err error) error {
+	if e, ok := err.(*net.OpError); ok {
+		err = e.Err
+	}
+	if e, ok := err.(*os.SyscallError); ok {
+		err = e.Err
+	}
+	return err
+}
+
+// fingerprint returns the short content hash of the given file, empty if unreadable.
+func fingerprint(filename string) string {
+	file, err := os.Open(filename)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:8]
+}
@@ -0,0 +1,27 @@
+package rex
+
+import (
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+// Upgrader upgrades the HTTP connections to the WebSocket protocol for Upgrade.
+// With CheckOrigin left nil, cross-origin requests (whose Origin host differs from
+// the Host header) are rejected. NOTE browsers send the cookies along with
+// cross-origin WebSocket handshakes, only allow the origins you trust, e.g.
+//
+//	rex.Upgrader.CheckOrigin = func(r *http.Request) bool {
+//		return r.Header.Get("Origin") == "https://example.com"
+//	}
+var Upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// Upgrade upgrades the HTTP connection of the request to the WebSocket protocol
+// using the Upgrader, the response header (e.g. Set-Cookie) is optional. On failure,
+// an HTTP error response has been sent to the client already.
+func Upgrade(w http.ResponseWriter, r *http.Request, header http.Header) (*websocket.Conn, error) {
+	return Upgrader.Upgrade(w, r, header)
+}
@@ -0,0 +1,43 @@
+package rex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUpgrade(t *testing.T) {
+	Convey("rex.Upgrade", t, func() {
+		app := New()
+		app.Get("/echo", func(w http.ResponseWriter, r *http.Request) {
+			conn, err := Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			kind, message, _ := conn.ReadMessage()
+			conn.WriteMessage(kind, message)
+		})
+		server := httptest.NewServer(app)
+		defer server.Close()
+
+		address := "ws" + strings.TrimPrefix(server.URL, "http") + "/echo"
+		conn, _, err := websocket.DefaultDialer.Dial(address, nil)
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		conn.WriteMessage(websocket.TextMessage, []byte("rex"))
+		_, message, err := conn.ReadMessage()
+		So(err, ShouldBeNil)
+		So(string(message), ShouldEqual, "rex")
+
+		// cross-origin handshakes are rejected by default.
+		_, response, err := websocket.DefaultDialer.Dial(address, http.Header{"Origin": {"http://example.com"}})
+		So(err, ShouldNotBeNil)
+		So(response.StatusCode, ShouldEqual, http.StatusForbidden)
+	})
+}